@@ -1,10 +1,33 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const DefaultJWTSecret = "your-secret-key"
+
+// RefreshTokenTTL 是刷新令牌的有效期，退役的签名密钥至少要保留这么久
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+const (
+	EmailVerificationOff      = "off"
+	EmailVerificationReadOnly = "read_only"
+	EmailVerificationBlock    = "block"
+)
+
+type OIDCProviderConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
 type Config struct {
 	DatabaseURL    string
 	JWTSecret      string
@@ -13,6 +36,45 @@ type Config struct {
 	CORSOrigins    []string
 	UploadPath     string
 	MaxUploadSize  int64
+
+	MailDriver       string
+	MailFrom         string
+	MailLogPath      string
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	PasswordResetURL string
+
+	EmailVerificationURL         string
+	EmailVerificationPolicy      string
+	EmailVerificationGracePeriod time.Duration
+
+	TOTPIssuer string
+
+	AccountDeletionGracePeriod time.Duration
+
+	LoginThrottleStore      string
+	LoginMaxAccountFailures int
+	LoginMaxIPFailures      int
+	LoginFailureWindow      time.Duration
+	LoginLockoutBase        time.Duration
+	LoginLockoutMax         time.Duration
+
+	OIDCProviders map[string]OIDCProviderConfig
+
+	JWTAlgorithm           string
+	JWTKeyRotationInterval time.Duration
+	JWTKeyRetention        time.Duration
+
+	ExchangeRateCSV          string
+	ExchangeRateSyncInterval time.Duration
+
+	RecurringBillInterval time.Duration
+
+	NotificationChannels      []string
+	NotificationWebhookURL    string
+	NotificationWebhookSecret string
 }
 
 func Load() *Config {
@@ -20,13 +82,92 @@ func Load() *Config {
 
 	return &Config{
 		DatabaseURL:   getEnv("DATABASE_URL", "finmind.db"),
-		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key"),
+		JWTSecret:     getEnv("JWT_SECRET", DefaultJWTSecret),
 		Port:          getEnv("PORT", "8080"),
 		Environment:   getEnv("ENVIRONMENT", "development"),
 		CORSOrigins:   []string{getEnv("CORS_ORIGINS", "*")},
 		UploadPath:    getEnv("UPLOAD_PATH", "./uploads"),
 		MaxUploadSize: maxUploadSize,
+
+		MailDriver:       getEnv("MAIL_DRIVER", "log"),
+		MailFrom:         getEnv("MAIL_FROM", "FinMind <no-reply@finmind.app>"),
+		MailLogPath:      getEnv("MAIL_LOG_PATH", ""),
+		SMTPHost:         getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "finmind://reset-password"),
+
+		EmailVerificationURL:         getEnv("EMAIL_VERIFICATION_URL", "finmind://verify-email"),
+		EmailVerificationPolicy:      getEnv("EMAIL_VERIFICATION_POLICY", EmailVerificationOff),
+		EmailVerificationGracePeriod: getDurationEnv("EMAIL_VERIFICATION_GRACE_PERIOD", 72*time.Hour),
+
+		TOTPIssuer: getEnv("TOTP_ISSUER", "FinMind"),
+
+		AccountDeletionGracePeriod: getDurationEnv("ACCOUNT_DELETION_GRACE_PERIOD", 7*24*time.Hour),
+
+		LoginThrottleStore:      getEnv("LOGIN_THROTTLE_STORE", "memory"),
+		LoginMaxAccountFailures: getIntEnv("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:      getIntEnv("LOGIN_MAX_IP_FAILURES", 20),
+		LoginFailureWindow:      getDurationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutBase:        getDurationEnv("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:         getDurationEnv("LOGIN_LOCKOUT_MAX", time.Hour),
+
+		OIDCProviders: loadOIDCProviders(),
+
+		JWTAlgorithm:           getEnv("JWT_ALGORITHM", "RS256"),
+		JWTKeyRotationInterval: getDurationEnv("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		// 退役密钥需至少保留一个刷新令牌有效期，保证已签发的令牌仍可校验
+		JWTKeyRetention: getDurationEnv("JWT_KEY_RETENTION", 8*24*time.Hour),
+
+		ExchangeRateCSV:          getEnv("EXCHANGE_RATE_CSV", ""),
+		ExchangeRateSyncInterval: getDurationEnv("EXCHANGE_RATE_SYNC_INTERVAL", 24*time.Hour),
+
+		RecurringBillInterval: getDurationEnv("RECURRING_BILL_INTERVAL", 5*time.Minute),
+
+		NotificationChannels:      strings.Split(getEnv("NOTIFICATION_CHANNELS", "inapp"), ","),
+		NotificationWebhookURL:    getEnv("NOTIFICATION_WEBHOOK_URL", ""),
+		NotificationWebhookSecret: getEnv("NOTIFICATION_WEBHOOK_SECRET", ""),
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.Environment == "production" && (c.JWTSecret == DefaultJWTSecret || c.JWTSecret == "") {
+		return errors.New("JWT_SECRET must be changed from the default value in production")
 	}
+	switch c.EmailVerificationPolicy {
+	case EmailVerificationOff, EmailVerificationReadOnly, EmailVerificationBlock:
+	default:
+		return fmt.Errorf("EMAIL_VERIFICATION_POLICY must be one of %s, %s or %s",
+			EmailVerificationOff, EmailVerificationReadOnly, EmailVerificationBlock)
+	}
+	if c.JWTKeyRotationInterval <= 0 {
+		return errors.New("JWT_KEY_ROTATION_INTERVAL must be positive")
+	}
+	if c.JWTKeyRetention < RefreshTokenTTL {
+		return fmt.Errorf("JWT_KEY_RETENTION must be at least the refresh token lifetime (%s)", RefreshTokenTTL)
+	}
+	return nil
+}
+
+func loadOIDCProviders() map[string]OIDCProviderConfig {
+	providers := make(map[string]OIDCProviderConfig)
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = OIDCProviderConfig{
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+	}
+	return providers
 }
 
 func getEnv(key, defaultValue string) string {
@@ -34,4 +175,22 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
 }
\ No newline at end of file
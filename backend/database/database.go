@@ -31,9 +31,36 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		return err
+	}
+
+	if err := runMigrations(db, preSchemaMigrations); err != nil {
+		return err
+	}
+
 	return db.AutoMigrate(
 		&models.User{},
 		&models.Category{},
+		&models.Account{},
+		&models.Goal{},
 		&models.Bill{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.MFAChallenge{},
+		&models.RecoveryCode{},
+		&models.AccountDeletionToken{},
+		&models.LoginAttempt{},
+		&models.UserIdentity{},
+		&models.OIDCAuthState{},
+		&models.SigningKey{},
+		&models.PersonalAccessToken{},
+		&models.ExchangeRate{},
+		&models.Budget{},
+		&models.BudgetAlert{},
+		&models.Notification{},
+		&models.RecurringRule{},
 	)
 }
\ No newline at end of file
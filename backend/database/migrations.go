@@ -0,0 +1,251 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"finmind-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AutoMigrate 只会新增表和字段，修改约束、转换数据等变更需要登记为一次性迁移
+type migration struct {
+	ID  string
+	Run func(tx *gorm.DB) error
+}
+
+// 在 AutoMigrate 之前执行，用于调整已有表结构
+var preSchemaMigrations = []migration{
+	{ID: "202610_bill_transfer_type", Run: migrateBillTransferType},
+	{ID: "202610_money_minor_units", Run: migrateMoneyMinorUnits},
+	{ID: "202610_backfill_email_verified", Run: migrateBackfillEmailVerified},
+	{ID: "202610_backfill_password_set", Run: migrateBackfillPasswordSet},
+	{ID: "202610_unique_account_names", Run: migrateUniqueAccountNames},
+	{ID: "202610_account_currency", Run: migrateAccountCurrency},
+	{ID: "202610_oidc_client_pkce", Run: migrateOIDCClientPKCE},
+}
+
+func runMigrations(db *gorm.DB, migrations []migration) error {
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&models.SchemaMigration{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("[Migrate] Applied migration %s", m.ID)
+	}
+	return nil
+}
+
+// 账单类型新增 transfer，转账没有分类：删除旧的类型检查约束（由 AutoMigrate 重建）并允许 category_id 为空
+func migrateBillTransferType(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&models.Bill{}) {
+		return nil
+	}
+
+	if migrator.HasConstraint(&models.Bill{}, "chk_bills_type") {
+		if err := migrator.DropConstraint(&models.Bill{}, "chk_bills_type"); err != nil {
+			return err
+		}
+	}
+	return migrator.AlterColumn(&models.Bill{}, "CategoryID")
+}
+
+// 金额由浮点数改为以分为单位的整数：新增 *_minor 列并按四舍五入换算旧数据，再删除旧列（NOT NULL 和检查约束由 AutoMigrate 补齐）
+func migrateMoneyMinorUnits(tx *gorm.DB) error {
+	if err := convertToMinorUnits(tx, &models.Bill{}, "amount", "amount_minor", "chk_bills_amount"); err != nil {
+		return err
+	}
+	return convertToMinorUnits(tx, &models.Account{}, "opening_balance", "opening_balance_minor", "")
+}
+
+// 引入邮箱验证前注册的用户没有验证记录，视为在注册时已验证，避免宽限期结束后被限制访问。
+// 只在 email_verified_at 列尚不存在时执行，之后注册的未验证用户不受影响
+func migrateBackfillEmailVerified(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&models.User{}) || migrator.HasColumn(&models.User{}, "EmailVerifiedAt") {
+		return nil
+	}
+
+	if err := migrator.AddColumn(&models.User{}, "EmailVerifiedAt"); err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Unscoped().Where("email_verified_at IS NULL").
+		UpdateColumn("email_verified_at", gorm.Expr("created_at")).Error
+}
+
+// 新增 password_set_at 区分用户是否知道自己的密码。第三方登录创建的账号使用随机密码，
+// 其身份关联与账号在同一事务中创建；注册后一分钟内没有关联第三方身份的账号视为已设置密码
+func migrateBackfillPasswordSet(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&models.User{}) || migrator.HasColumn(&models.User{}, "PasswordSetAt") {
+		return nil
+	}
+
+	if err := migrator.AddColumn(&models.User{}, "PasswordSetAt"); err != nil {
+		return err
+	}
+	if !migrator.HasTable(&models.UserIdentity{}) {
+		return tx.Model(&models.User{}).Unscoped().Where("1 = 1").
+			UpdateColumn("password_set_at", gorm.Expr("created_at")).Error
+	}
+
+	var users []models.User
+	if err := tx.Unscoped().Select("id", "created_at").Find(&users).Error; err != nil {
+		return err
+	}
+	var identities []models.UserIdentity
+	if err := tx.Select("user_id", "created_at").Find(&identities).Error; err != nil {
+		return err
+	}
+	firstLinked := make(map[uint]time.Time)
+	for _, identity := range identities {
+		if linked, ok := firstLinked[identity.UserID]; !ok || identity.CreatedAt.Before(linked) {
+			firstLinked[identity.UserID] = identity.CreatedAt
+		}
+	}
+
+	for _, user := range users {
+		if linked, ok := firstLinked[user.ID]; ok && linked.Sub(user.CreatedAt) < time.Minute {
+			continue
+		}
+		if err := tx.Model(&models.User{}).Unscoped().Where("id = ?", user.ID).
+			UpdateColumn("password_set_at", user.CreatedAt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 账户名称改为按用户唯一：创建唯一索引前为已有的重名账户（保留最早的一个）在名称后追加编号
+func migrateUniqueAccountNames(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(&models.Account{}) {
+		return nil
+	}
+
+	var accounts []models.Account
+	if err := tx.Select("id", "user_id", "name").Order("id ASC").Find(&accounts).Error; err != nil {
+		return err
+	}
+
+	type accountKey struct {
+		userID uint
+		name   string
+	}
+	seen := make(map[accountKey]bool, len(accounts))
+	for _, account := range accounts {
+		seen[accountKey{account.UserID, account.Name}] = true
+	}
+	kept := make(map[accountKey]bool, len(accounts))
+	for _, account := range accounts {
+		key := accountKey{account.UserID, account.Name}
+		if !kept[key] {
+			kept[key] = true
+			continue
+		}
+
+		name := account.Name
+		for n := 2; seen[accountKey{account.UserID, name}]; n++ {
+			name = fmt.Sprintf("%s (%d)", account.Name, n)
+		}
+		seen[accountKey{account.UserID, name}] = true
+		if err := tx.Model(&models.Account{}).Where("id = ?", account.ID).UpdateColumn("name", name).Error; err != nil {
+			return err
+		}
+		log.Printf("[Migrate] Renamed duplicate account %d to %q", account.ID, name)
+	}
+	return nil
+}
+
+// 账户新增币种：已有账户取其账单中最常用的币种，没有账单时使用用户的本位币
+func migrateAccountCurrency(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&models.Account{}) || migrator.HasColumn(&models.Account{}, "Currency") {
+		return nil
+	}
+
+	if err := migrator.AddColumn(&models.Account{}, "Currency"); err != nil {
+		return err
+	}
+	if migrator.HasColumn(&models.User{}, "BaseCurrency") {
+		if err := tx.Exec("UPDATE accounts SET currency = (SELECT base_currency FROM users WHERE users.id = accounts.user_id) " +
+			"WHERE EXISTS (SELECT 1 FROM users WHERE users.id = accounts.user_id)").Error; err != nil {
+			return err
+		}
+	}
+	if !migrator.HasTable(&models.Bill{}) || !migrator.HasColumn(&models.Bill{}, "Currency") {
+		return nil
+	}
+
+	var usages []struct {
+		AccountID uint
+		Currency  string
+		Count     int64
+	}
+	if err := tx.Model(&models.Bill{}).Unscoped().Select("account_id, currency, COUNT(*) AS count").
+		Where("account_id IS NOT NULL").Group("account_id, currency").Order("account_id, count DESC, currency").
+		Scan(&usages).Error; err != nil {
+		return err
+	}
+	for i, usage := range usages {
+		if i > 0 && usages[i-1].AccountID == usage.AccountID {
+			continue
+		}
+		if err := tx.Model(&models.Account{}).Unscoped().Where("id = ?", usage.AccountID).
+			UpdateColumn("currency", usage.Currency).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PKCE 的 code_verifier 改由客户端保存：进行中的授权只保存了 verifier，无法再完成，直接重建该表
+func migrateOIDCClientPKCE(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&models.OIDCAuthState{}) || !migrator.HasColumn(&models.OIDCAuthState{}, "code_verifier") {
+		return nil
+	}
+	return migrator.DropTable(&models.OIDCAuthState{})
+}
+
+func convertToMinorUnits(tx *gorm.DB, model interface{}, oldColumn, newColumn, constraint string) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(model) || !migrator.HasColumn(model, oldColumn) || migrator.HasColumn(model, newColumn) {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	table := clause.Table{Name: stmt.Schema.Table}
+
+	if err := tx.Exec("ALTER TABLE ? ADD COLUMN ? bigint", table, clause.Column{Name: newColumn}).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("UPDATE ? SET ? = CAST(ROUND(? * 100) AS BIGINT)", table, clause.Column{Name: newColumn}, clause.Column{Name: oldColumn}).Error; err != nil {
+		return err
+	}
+
+	if constraint != "" && migrator.HasConstraint(model, constraint) {
+		if err := migrator.DropConstraint(model, constraint); err != nil {
+			return err
+		}
+	}
+	return migrator.DropColumn(model, oldColumn)
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"time"
+
+	"finmind-backend/models"
+	"gorm.io/gorm"
+)
+
+// 用户删除时需要一并物理删除的数据表，新增归属于用户的表时需在此登记，并加入数据导出（handlers/user_data.go 的 writeExport）
+func userOwnedModels() []interface{} {
+	return []interface{}{
+		&models.Bill{},
+		&models.RecurringRule{},
+		&models.Notification{},
+		&models.BudgetAlert{},
+		&models.Budget{},
+		&models.Goal{},
+		&models.Account{},
+		&models.Category{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.MFAChallenge{},
+		&models.RecoveryCode{},
+		&models.AccountDeletionToken{},
+		&models.UserIdentity{},
+		&models.PersonalAccessToken{},
+		&models.ExchangeRate{},
+	}
+}
+
+func PurgeUser(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range userOwnedModels() {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&models.User{}, userID).Error
+	})
+}
+
+func PurgeScheduledUsers(db *gorm.DB, now time.Time) (int, error) {
+	var userIDs []uint
+	if err := db.Unscoped().Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	for i, userID := range userIDs {
+		if err := PurgeUser(db, userID); err != nil {
+			return i, err
+		}
+	}
+	return len(userIDs), nil
+}
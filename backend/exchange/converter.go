@@ -0,0 +1,130 @@
+package exchange
+
+import (
+	"time"
+
+	"finmind-backend/models"
+	"finmind-backend/money"
+	"gorm.io/gorm"
+)
+
+// Converter 按账单日期换算金额：优先使用当天或之前最近一天的汇率，用户手动录入的汇率优先于共享汇率。
+// 缺少直接汇率时尝试反向汇率，再通过汇率表中出现过的币种交叉换算。结果按请求缓存，不可跨请求复用
+type Converter struct {
+	db     *gorm.DB
+	userID uint
+	cache  map[string]rateResult
+	pivots []string
+}
+
+type rateResult struct {
+	rate  float64
+	found bool
+}
+
+func NewConverter(db *gorm.DB, userID uint) *Converter {
+	return &Converter{db: db, userID: userID, cache: make(map[string]rateResult)}
+}
+
+// Convert 换算后的金额四舍五入到目标币种的最小单位
+func (c *Converter) Convert(amount money.Amount, from, to string, at time.Time) (money.Amount, bool, error) {
+	rate, ok, err := c.Rate(from, to, at)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	return amount.Mul(rate), true, nil
+}
+
+func (c *Converter) Rate(from, to string, at time.Time) (float64, bool, error) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == to {
+		return 1, true, nil
+	}
+
+	date := at.UTC().Format(models.ExchangeRateDateFormat)
+	key := from + to + date
+	if cached, ok := c.cache[key]; ok {
+		return cached.rate, cached.found, nil
+	}
+
+	rate, found, err := c.directRate(from, to, date)
+	if err != nil {
+		return 0, false, err
+	}
+	if !found {
+		rate, found, err = c.crossRate(from, to, date)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	c.cache[key] = rateResult{rate: rate, found: found}
+	return rate, found, nil
+}
+
+func (c *Converter) directRate(from, to, date string) (float64, bool, error) {
+	var rates []models.ExchangeRate
+	if err := c.db.Where("user_id IN ? AND date <= ?", []uint{0, c.userID}, date).
+		Where("(base = ? AND quote = ?) OR (base = ? AND quote = ?)", from, to, to, from).
+		Order("date DESC, user_id DESC").
+		Limit(1).
+		Find(&rates).Error; err != nil {
+		return 0, false, err
+	}
+	if len(rates) == 0 {
+		return 0, false, nil
+	}
+
+	if rates[0].Base == from {
+		return rates[0].Rate, true, nil
+	}
+	return 1 / rates[0].Rate, true, nil
+}
+
+func (c *Converter) crossRate(from, to, date string) (float64, bool, error) {
+	if c.pivots == nil {
+		var bases, quotes []string
+		if err := c.db.Model(&models.ExchangeRate{}).
+			Where("user_id IN ?", []uint{0, c.userID}).
+			Distinct().
+			Pluck("base", &bases).Error; err != nil {
+			return 0, false, err
+		}
+		if err := c.db.Model(&models.ExchangeRate{}).
+			Where("user_id IN ?", []uint{0, c.userID}).
+			Distinct().
+			Pluck("quote", &quotes).Error; err != nil {
+			return 0, false, err
+		}
+
+		seen := make(map[string]bool)
+		c.pivots = []string{}
+		for _, currency := range append(bases, quotes...) {
+			if !seen[currency] {
+				seen[currency] = true
+				c.pivots = append(c.pivots, currency)
+			}
+		}
+	}
+
+	for _, pivot := range c.pivots {
+		if pivot == from || pivot == to {
+			continue
+		}
+		fromPivot, ok, err := c.directRate(from, pivot, date)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			continue
+		}
+		pivotTo, ok, err := c.directRate(pivot, to, date)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			return fromPivot * pivotTo, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,102 @@
+package exchange
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"finmind-backend/models"
+)
+
+type Rate struct {
+	Date  string
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+// Provider 从外部数据源获取历史汇率，由 Sync 写入汇率表
+type Provider interface {
+	Name() string
+	Rates(ctx context.Context) ([]Rate, error)
+}
+
+// CSVProvider 读取本地 CSV 文件，适用于离线环境。文件格式为 date,base,quote,rate，可带表头
+type CSVProvider struct {
+	Path string
+}
+
+func NewCSVProvider(path string) *CSVProvider {
+	return &CSVProvider{Path: path}
+}
+
+func (p *CSVProvider) Name() string {
+	return "csv"
+}
+
+func (p *CSVProvider) Rates(ctx context.Context) ([]Rate, error) {
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseCSV(file)
+}
+
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var rates []Rate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+
+		rate, err := parseRate(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
+
+func parseRate(record []string) (Rate, error) {
+	date, err := time.Parse(models.ExchangeRateDateFormat, strings.TrimSpace(record[0]))
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid date %q", record[0])
+	}
+
+	base := NormalizeCurrency(record[1])
+	quote := NormalizeCurrency(record[2])
+	if len(base) != 3 || len(quote) != 3 || base == quote {
+		return Rate{}, fmt.Errorf("invalid currency pair %s/%s", record[1], record[2])
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+	if err != nil || value <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", record[3])
+	}
+
+	return Rate{Date: date.Format(models.ExchangeRateDateFormat), Base: base, Quote: quote, Rate: value}, nil
+}
+
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,46 @@
+package exchange
+
+import (
+	"context"
+
+	"finmind-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const importBatchSize = 500
+
+// Sync 将数据源的汇率写入共享汇率表，同一币种对同一天的汇率以最新导入为准
+func Sync(ctx context.Context, db *gorm.DB, provider Provider) (int, error) {
+	rates, err := provider.Rates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Import(db, provider.Name(), rates)
+}
+
+func Import(db *gorm.DB, source string, rates []Rate) (int, error) {
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	records := make([]models.ExchangeRate, len(rates))
+	for i, rate := range rates {
+		records[i] = models.ExchangeRate{
+			Base:   rate.Base,
+			Quote:  rate.Quote,
+			Date:   rate.Date,
+			Rate:   rate.Rate,
+			Source: source,
+		}
+	}
+
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "base"}, {Name: "quote"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(records, importBatchSize).Error
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
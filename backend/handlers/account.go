@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+const maxAccountBalanceDays = 366
+
+// 账单对 account_id 所指账户余额的影响：收入为正，支出和转出为负，转入部分另按 to_account_id 汇总
+const signedAmountExpr = "CASE WHEN type = 'income' THEN amount_minor ELSE -amount_minor END"
+
+var errInvalidAccount = errors.New("invalid account")
+
+type AccountHandler struct {
+	db *gorm.DB
+}
+
+func NewAccountHandler(db *gorm.DB) *AccountHandler {
+	return &AccountHandler{db: db}
+}
+
+func (h *AccountHandler) GetAccounts(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	query := h.db.Where("user_id = ?", userID)
+	if c.Query("include_archived") != "true" {
+		query = query.Where("archived = ?", false)
+	}
+
+	var accounts []models.Account
+	if err := query.Order("created_at ASC").Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+
+	balances, err := accountBalances(h.db, userID, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	var total money.Amount
+	accountResponses := make([]models.AccountResponse, len(accounts))
+	for i, account := range accounts {
+		accountResponses[i] = account.ToResponse(account.OpeningBalance + balances[account.ID])
+		total += accountResponses[i].Balance
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": accountResponses, "total_balance": total})
+}
+
+func (h *AccountHandler) GetAccount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	account, ok := h.loadAccount(c, userID)
+	if !ok {
+		return
+	}
+
+	balances, err := accountBalances(h.db, userID, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account.ToResponse(account.OpeningBalance+balances[account.ID]))
+}
+
+func (h *AccountHandler) CreateAccount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if accountNameTaken(h.db, userID, req.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account with this name already exists"})
+		return
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		if currency, err = userBaseCurrency(h.db, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+			return
+		}
+	}
+
+	account := models.Account{
+		UserID:         userID,
+		Name:           req.Name,
+		Type:           req.Type,
+		Institution:    req.Institution,
+		Currency:       currency,
+		OpeningBalance: req.OpeningBalance,
+		Icon:           req.Icon,
+		Color:          req.Color,
+	}
+
+	if err := h.db.Create(&account).Error; err != nil {
+		// 并发创建同名账户时由唯一索引拦截
+		if accountNameTaken(h.db, userID, req.Name, 0) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account.ToResponse(account.OpeningBalance))
+}
+
+func (h *AccountHandler) UpdateAccount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, ok := h.loadAccount(c, userID)
+	if !ok {
+		return
+	}
+
+	if req.Name != "" && req.Name != account.Name {
+		if accountNameTaken(h.db, userID, req.Name, account.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account with this name already exists"})
+			return
+		}
+		account.Name = req.Name
+	}
+	if req.Type != "" {
+		account.Type = req.Type
+	}
+	if req.Institution != nil {
+		account.Institution = *req.Institution
+	}
+	// 已有账单（含已删除的）的账户不能更换币种，否则余额会混合不同币种的金额
+	if req.Currency != "" && req.Currency != account.Currency {
+		var billCount int64
+		if err := h.db.Unscoped().Model(&models.Bill{}).Where("account_id = ? OR to_account_id = ?", account.ID, account.ID).
+			Count(&billCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account usage"})
+			return
+		}
+		if billCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot change the currency of an account with existing bills"})
+			return
+		}
+		account.Currency = req.Currency
+	}
+	if req.OpeningBalance != nil {
+		account.OpeningBalance = *req.OpeningBalance
+	}
+	if req.Icon != "" {
+		account.Icon = req.Icon
+	}
+	if req.Color != "" {
+		account.Color = req.Color
+	}
+	if req.Archived != nil {
+		account.Archived = *req.Archived
+	}
+
+	if err := h.db.Save(&account).Error; err != nil {
+		if accountNameTaken(h.db, userID, account.Name, account.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return
+	}
+
+	balances, err := accountBalances(h.db, userID, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account.ToResponse(account.OpeningBalance+balances[account.ID]))
+}
+
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	account, ok := h.loadAccount(c, userID)
+	if !ok {
+		return
+	}
+
+	// 已删除的账单也会计入，它们可能通过同步恢复，不能指向已删除的账户
+	var billCount int64
+	if err := h.db.Unscoped().Model(&models.Bill{}).Where("account_id = ? OR to_account_id = ?", account.ID, account.ID).Count(&billCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account usage"})
+		return
+	}
+
+	// 有账单的账户删除后余额将无法追溯，只允许归档
+	if billCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete account with existing bills, archive it instead"})
+		return
+	}
+
+	if err := h.db.Delete(&account).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+func (h *AccountHandler) GetAccountBalances(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.AccountBalancesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	endDate := today
+	startDate := today.AddDate(0, 0, -29)
+	if query.EndDate != "" {
+		if endDate, err = time.Parse("2006-01-02", query.EndDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date"})
+			return
+		}
+		startDate = endDate.AddDate(0, 0, -29)
+	}
+	if query.StartDate != "" {
+		if startDate, err = time.Parse("2006-01-02", query.StartDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date"})
+			return
+		}
+	}
+	if endDate.Before(startDate) || endDate.Sub(startDate) >= maxAccountBalanceDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range must be between 1 and 366 days"})
+		return
+	}
+
+	account, ok := h.loadAccount(c, userID)
+	if !ok {
+		return
+	}
+
+	before, err := accountBalances(h.db, userID, startDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+
+	days := make([]models.AccountDailyBalance, 0, int(endDate.Sub(startDate).Hours()/24)+1)
+	dayIndex := make(map[string]int)
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		dayIndex[day.Format("2006-01-02")] = len(days)
+		days = append(days, models.AccountDailyBalance{Date: day.Format("2006-01-02")})
+	}
+
+	var bills []models.Bill
+	if err := h.db.Select("type", "amount_minor", "account_id", "bill_time").
+		Where("user_id = ? AND (account_id = ? OR to_account_id = ?) AND bill_time >= ? AND bill_time < ?", userID, account.ID, account.ID, startDate, endDate.AddDate(0, 0, 1)).
+		Find(&bills).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bills"})
+		return
+	}
+	for _, bill := range bills {
+		i, ok := dayIndex[bill.BillTime.UTC().Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		switch {
+		case bill.Type == models.BillTypeIncome:
+			days[i].Income += bill.Amount
+		case bill.Type == models.BillTypeExpense:
+			days[i].Expense += bill.Amount
+		case bill.AccountID != nil && *bill.AccountID == account.ID:
+			days[i].TransferOut += bill.Amount
+		default:
+			days[i].TransferIn += bill.Amount
+		}
+	}
+
+	openingBalance := account.OpeningBalance + before[account.ID]
+	balance := openingBalance
+	for i := range days {
+		balance += days[i].Income - days[i].Expense + days[i].TransferIn - days[i].TransferOut
+		days[i].Balance = balance
+	}
+
+	c.JSON(http.StatusOK, models.AccountBalancesResponse{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		StartDate:      startDate.Format("2006-01-02"),
+		EndDate:        endDate.Format("2006-01-02"),
+		OpeningBalance: openingBalance,
+		ClosingBalance: balance,
+		Days:           days,
+	})
+}
+
+func (h *AccountHandler) loadAccount(c *gin.Context, userID uint) (models.Account, bool) {
+	var account models.Account
+
+	accountID, err := middleware.GetUserIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return account, false
+	}
+
+	if err := h.db.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return account, false
+	}
+	return account, true
+}
+
+// accountNameTaken 判断用户是否已有同名的未删除账户，excludeID 为正在修改的账户
+func accountNameTaken(db *gorm.DB, userID uint, name string, excludeID uint) bool {
+	var count int64
+	if err := db.Model(&models.Account{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).
+		Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// accountBalances 汇总账单对各账户余额的净影响（不含期初余额），before 非零时只统计此前的账单
+func accountBalances(db *gorm.DB, userID uint, before time.Time) (map[uint]money.Amount, error) {
+	type accountSum struct {
+		AccountID uint
+		Total     money.Amount
+	}
+
+	query := db.Model(&models.Bill{}).
+		Select("account_id, SUM("+signedAmountExpr+") as total").
+		Where("user_id = ? AND account_id IS NOT NULL", userID)
+	if !before.IsZero() {
+		query = query.Where("bill_time < ?", before)
+	}
+
+	var sums []accountSum
+	if err := query.Group("account_id").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+
+	incomingQuery := db.Model(&models.Bill{}).
+		Select("to_account_id as account_id, SUM(amount_minor) as total").
+		Where("user_id = ? AND type = ? AND to_account_id IS NOT NULL", userID, models.BillTypeTransfer)
+	if !before.IsZero() {
+		incomingQuery = incomingQuery.Where("bill_time < ?", before)
+	}
+
+	var incoming []accountSum
+	if err := incomingQuery.Group("to_account_id").Scan(&incoming).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]money.Amount, len(sums)+len(incoming))
+	for _, sum := range append(sums, incoming...) {
+		balances[sum.AccountID] += sum.Total
+	}
+	return balances, nil
+}
+
+// validateBillAccount 校验账单关联的账户属于当前用户，accountID 为 0 表示不关联账户
+func validateBillAccount(db *gorm.DB, userID uint, accountID *uint) (*uint, error) {
+	account, err := loadBillAccount(db, userID, accountID)
+	if err != nil || account == nil {
+		return nil, err
+	}
+	return &account.ID, nil
+}
+
+// loadBillAccount 与 validateBillAccount 相同，但返回账户本身，用于校验币种
+func loadBillAccount(db *gorm.DB, userID uint, accountID *uint) (*models.Account, error) {
+	if accountID == nil || *accountID == 0 {
+		return nil, nil
+	}
+
+	var account models.Account
+	if err := db.Where("id = ? AND user_id = ?", *accountID, userID).First(&account).Error; err != nil {
+		return nil, errInvalidAccount
+	}
+	return &account, nil
+}
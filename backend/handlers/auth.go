@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,17 +14,28 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"finmind-backend/config"
+	"finmind-backend/jwtkeys"
+	"finmind-backend/mailer"
 	"finmind-backend/middleware"
 	"finmind-backend/models"
+	"finmind-backend/throttle"
+)
+
+const (
+	accessTokenTTL  = 1 * time.Hour
+	refreshTokenTTL = config.RefreshTokenTTL
 )
 
 type AuthHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db         *gorm.DB
+	cfg        *config.Config
+	mailer     mailer.Mailer
+	loginGuard *throttle.LoginGuard
+	keys       *jwtkeys.Manager
 }
 
-func NewAuthHandler(db *gorm.DB, cfg *config.Config) *AuthHandler {
-	return &AuthHandler{db: db, cfg: cfg}
+func NewAuthHandler(db *gorm.DB, cfg *config.Config, m mailer.Mailer, loginGuard *throttle.LoginGuard, keys *jwtkeys.Manager) *AuthHandler {
+	return &AuthHandler{db: db, cfg: cfg, mailer: m, loginGuard: loginGuard, keys: keys}
 }
 
 type LoginRequest struct {
@@ -58,10 +74,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	now := time.Now()
 	user := models.User{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: string(hashedPassword),
+		Name:          req.Name,
+		Email:         req.Email,
+		Password:      string(hashedPassword),
+		PasswordSetAt: &now,
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
@@ -69,7 +87,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.generateTokens(user.ID, user.Email)
+	if err := h.sendEmailVerification(user); err != nil {
+		log.Printf("[Register] Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	accessToken, refreshToken, err := h.generateTokens(c, user.ID, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -89,18 +111,44 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	now := time.Now()
+	retryAfter, err := h.loginGuard.Check(req.Email, c.ClientIP(), now)
+	if err != nil {
+		log.Printf("[Login] Throttle check failed: %v", err)
+	}
+	if retryAfter > 0 {
+		setRetryAfter(c, retryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, please try again later"})
+		return
+	}
+
 	var user models.User
 	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		h.loginFailed(c, req.Email, now)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		h.loginFailed(c, req.Email, now)
+		return
+	}
+
+	// 开启两步验证时密码正确还不算登录成功，失败计数在 VerifyMFA 通过后才清零
+	if user.TOTPEnabledAt != nil {
+		challenge, err := h.createMFAChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create MFA challenge"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
 		return
 	}
 
-	accessToken, refreshToken, err := h.generateTokens(user.ID, user.Email)
+	if err := h.loginGuard.Succeed(req.Email); err != nil {
+		log.Printf("[Login] Failed to reset throttle for user %d: %v", user.ID, err)
+	}
+
+	accessToken, refreshToken, err := h.generateTokens(c, user.ID, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -113,6 +161,22 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+func (h *AuthHandler) ValidateToken(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
@@ -137,8 +201,9 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	var req struct {
-		Name   string `json:"name" binding:"omitempty,min=2"`
-		Avatar string `json:"avatar"`
+		Name         string `json:"name" binding:"omitempty,min=2"`
+		Avatar       string `json:"avatar"`
+		BaseCurrency string `json:"base_currency" binding:"omitempty,iso4217,supported_currency"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -156,6 +221,9 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		user.Name = req.Name
 	}
 	user.Avatar = req.Avatar
+	if req.BaseCurrency != "" {
+		user.BaseCurrency = req.BaseCurrency
+	}
 
 	if err := h.db.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
@@ -182,9 +250,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	claims := &middleware.Claims{}
-	token, err := jwt.ParseWithClaims(req.RefreshToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(h.cfg.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(req.RefreshToken, claims, h.keys.Keyfunc)
 
 	if err != nil || !token.Valid {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
@@ -196,13 +262,64 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	if claims.ID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
 	var user models.User
 	if err := h.db.First(&user, claims.UserID).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
-	accessToken, refreshToken, err := h.generateTokens(user.ID, user.Email)
+	var stored models.RefreshToken
+	if err := h.db.Where("jti = ? AND user_id = ?", claims.ID, user.ID).First(&stored).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	now := time.Now()
+	// 原子地将令牌标记为已使用，保证每个刷新令牌只能使用一次
+	result := h.db.Model(&models.RefreshToken{}).
+		Where("jti = ? AND used_at IS NULL", stored.JTI).
+		Update("used_at", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate refresh token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		// 已轮换过的刷新令牌被重放，视为泄露并注销整个令牌族
+		log.Printf("[RefreshToken] Reuse detected for session %d of user %d", stored.SessionID, user.ID)
+		if err := revokeSessions(h.db, "id = ?", stored.SessionID); err != nil {
+			log.Printf("[RefreshToken] Failed to revoke session %d: %v", stored.SessionID, err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected"})
+		return
+	}
+
+	var session models.Session
+	if err := h.db.Where("id = ? AND user_id = ?", stored.SessionID, user.ID).First(&session).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if !session.IsActive(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+		return
+	}
+
+	if err := h.db.Model(&session).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"expires_at":   now.Add(refreshTokenTTL),
+		"ip_address":   c.ClientIP(),
+		"user_agent":   c.Request.UserAgent(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+		return
+	}
+
+	accessToken, refreshToken, err := h.signTokens(session.ID, user.ID, user.Email, stored.JTI)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -214,14 +331,50 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	})
 }
 
-func (h *AuthHandler) generateTokens(userID uint, email string) (string, string, error) {
+func (h *AuthHandler) generateTokens(c *gin.Context, userID uint, email string) (string, string, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     userID,
+		Device:     c.GetHeader("X-Device-Name"),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		IssuedAt:   now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+
+	if err := h.db.Create(&session).Error; err != nil {
+		return "", "", err
+	}
+
+	return h.signTokens(session.ID, userID, email, "")
+}
+
+func (h *AuthHandler) signTokens(sessionID, userID uint, email, parentJTI string) (string, string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if err := h.db.Create(&models.RefreshToken{
+		JTI:       jti,
+		SessionID: sessionID,
+		UserID:    userID,
+		ParentJTI: parentJTI,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}).Error; err != nil {
+		return "", "", err
+	}
+
 	accessClaims := middleware.Claims{
 		UserID: userID,
 		Email:  email,
 		TokenType: "access",
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -229,24 +382,88 @@ func (h *AuthHandler) generateTokens(userID uint, email string) (string, string,
 		UserID: userID,
 		Email:  email,
 		TokenType: "refresh",
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(refreshTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-
-	accessTokenString, err := accessToken.SignedString([]byte(h.cfg.JWTSecret))
+	accessTokenString, err := h.keys.Sign(accessClaims)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(h.cfg.JWTSecret))
+	refreshTokenString, err := h.keys.Sign(refreshClaims)
 	if err != nil {
 		return "", "", err
 	}
 
 	return accessTokenString, refreshTokenString, nil
-}
\ No newline at end of file
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, err := middleware.GetSessionID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := revokeSessions(h.db, "id = ?", sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := revokeSessions(h.db, "user_id = ?", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
+
+func (h *AuthHandler) loginFailed(c *gin.Context, email string, now time.Time) {
+	h.recordLoginFailure(c, email, now)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+}
+
+// recordLoginFailure 记录一次密码或第二因素验证失败，达到锁定阈值时设置 Retry-After
+func (h *AuthHandler) recordLoginFailure(c *gin.Context, email string, now time.Time) {
+	retryAfter, err := h.loginGuard.Fail(email, c.ClientIP(), now)
+	if err != nil {
+		log.Printf("[Login] Failed to record failed attempt: %v", err)
+	}
+	if retryAfter > 0 {
+		setRetryAfter(c, retryAfter)
+	}
+}
+
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+func revokeSessions(db *gorm.DB, query interface{}, args ...interface{}) error {
+	return db.Model(&models.Session{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
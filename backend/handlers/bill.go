@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +16,12 @@ import (
 )
 
 type BillHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	alerts *BudgetAlerter
 }
 
-func NewBillHandler(db *gorm.DB) *BillHandler {
-	return &BillHandler{db: db}
+func NewBillHandler(db *gorm.DB, alerts *BudgetAlerter) *BillHandler {
+	return &BillHandler{db: db, alerts: alerts}
 }
 
 func (h *BillHandler) GetBills(c *gin.Context) {
@@ -49,6 +52,9 @@ func (h *BillHandler) GetBills(c *gin.Context) {
 	if query.CategoryID > 0 {
 		db = db.Where("category_id = ?", query.CategoryID)
 	}
+	if query.AccountID > 0 {
+		db = db.Where("account_id = ? OR to_account_id = ?", query.AccountID, query.AccountID)
+	}
 	if query.StartDate != "" {
 		if startDate, err := time.Parse("2006-01-02", query.StartDate); err == nil {
 			db = db.Where("bill_time >= ?", startDate)
@@ -74,7 +80,7 @@ func (h *BillHandler) GetBills(c *gin.Context) {
 	if query.SortBy != "" {
 		switch query.SortBy {
 		case "amount":
-			orderBy = "amount"
+			orderBy = "amount_minor"
 		case "merchant":
 			orderBy = "merchant"
 		case "created_at":
@@ -124,6 +130,7 @@ func (h *BillHandler) GetBill(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", bill.ETag())
 	c.JSON(http.StatusOK, bill.ToResponse())
 }
 
@@ -146,19 +153,40 @@ func (h *BillHandler) CreateBill(c *gin.Context) {
 
 	log.Printf("[CreateBill] Parsed request: %+v", req)
 
-	var category models.Category
-	if err := h.db.First(&category, req.CategoryID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+	legs, failure := resolveBillLegs(h.db, userID, billLegs{
+		Type:        req.Type,
+		CategoryID:  optionalID(req.CategoryID),
+		AccountID:   req.AccountID,
+		ToAccountID: req.ToAccountID,
+		Currency:    req.Currency,
+	})
+	if failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+	currency := legs.Currency
+	if currency == "" {
+		if currency, err = userBaseCurrency(h.db, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+			return
+		}
+	}
+	goalID, err := validateBillGoal(h.db, userID, req.GoalID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal"})
 		return
 	}
-
-
 
 	bill := models.Bill{
 		UserID:      userID,
 		Type:        req.Type,
 		Amount:      req.Amount,
-		CategoryID:  req.CategoryID,
+		Currency:    currency,
+		CategoryID:  legs.CategoryID,
+		AccountID:   legs.AccountID,
+		ToAccountID: legs.ToAccountID,
+		GoalID:      goalID,
+		Channel:     req.Channel,
 		Merchant:    req.Merchant,
 		Description: req.Description,
 		BillTime:    req.BillTime,
@@ -177,7 +205,9 @@ func (h *BillHandler) CreateBill(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bill details"})
 		return
 	}
+	h.alerts.CheckBills(userID, bill)
 
+	c.Header("ETag", bill.ETag())
 	c.JSON(http.StatusCreated, bill.ToResponse())
 }
 
@@ -206,23 +236,64 @@ func (h *BillHandler) UpdateBill(c *gin.Context) {
 		return
 	}
 
-	updates := make(map[string]interface{})
+	if !h.checkBillPrecondition(c, &bill) {
+		return
+	}
 
+	// 在现有账单的基础上合并本次修改后整体校验，保证类型、分类与转出/转入账户保持一致
+	legs := billLegs{
+		Type:        bill.Type,
+		CategoryID:  bill.CategoryID,
+		AccountID:   bill.AccountID,
+		ToAccountID: bill.ToAccountID,
+		Currency:    bill.Currency,
+	}
+	if req.Currency != "" {
+		legs.Currency = req.Currency
+	}
+	if req.Type != "" {
+		legs.Type = req.Type
+	}
 	if req.CategoryID != 0 {
-		updates["category_id"] = req.CategoryID
-		// 验证分类是否存在且属于当前用户
-		var category models.Category
-		if err := h.db.Where("id = ? AND (user_id = ? OR user_id IS NULL)", req.CategoryID, userID).First(&category).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+		legs.CategoryID = &req.CategoryID
+	}
+	// account_id 传 0 表示解除与账户的关联
+	if req.AccountID != nil {
+		legs.AccountID = req.AccountID
+	}
+	if req.ToAccountID != nil {
+		legs.ToAccountID = req.ToAccountID
+	}
+
+	legs, failure := resolveBillLegs(h.db, userID, legs)
+	if failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"type":          legs.Type,
+		"category_id":   legs.CategoryID,
+		"account_id":    legs.AccountID,
+		"to_account_id": legs.ToAccountID,
+		"currency":      legs.Currency,
+	}
+
+	// goal_id 传 0 表示取消标记为储蓄目标的存取
+	if req.GoalID != nil {
+		goalID, err := validateBillGoal(h.db, userID, req.GoalID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal"})
 			return
 		}
+		updates["goal_id"] = goalID
 	}
 
-	if req.Type != "" {
-		updates["type"] = req.Type
+	if req.Channel != "" {
+		updates["channel"] = req.Channel
 	}
 	if req.Amount != 0 {
-		updates["amount"] = req.Amount
+		updates["amount_minor"] = req.Amount
 	}
 	if req.Merchant != "" {
 		updates["merchant"] = req.Merchant
@@ -233,17 +304,25 @@ func (h *BillHandler) UpdateBill(c *gin.Context) {
 	if !req.BillTime.IsZero() {
 		updates["bill_time"] = req.BillTime
 	}
+	updates["version"] = gorm.Expr("version + 1")
 
-	if err := h.db.Model(&bill).Updates(updates).Error; err != nil {
+	result := h.db.Model(&bill).Where("version = ?", bill.Version).Updates(updates)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bill"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		h.respondBillConflict(c, bill.ID)
+		return
+	}
 
 	if err := h.db.Preload("Category").First(&bill, bill.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bill details"})
 		return
 	}
+	h.alerts.CheckBills(userID, bill)
 
+	c.Header("ETag", bill.ETag())
 	c.JSON(http.StatusOK, bill.ToResponse())
 }
 
@@ -266,14 +345,296 @@ func (h *BillHandler) DeleteBill(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&bill).Error; err != nil {
+	if !h.checkBillPrecondition(c, &bill) {
+		return
+	}
+
+	result := h.db.Where("version = ?", bill.Version).Delete(&bill)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bill"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		h.respondBillConflict(c, bill.ID)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Bill deleted successfully"})
 }
 
+func (h *BillHandler) SyncBills(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.SyncBillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.SyncBillsResponse{
+		Results: make([]models.SyncBillResult, 0, len(req.Bills)),
+	}
+	var synced []models.Bill
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for i, item := range req.Bills {
+			result := models.SyncBillResult{ClientID: item.ClientID}
+
+			// 每条账单使用独立的保存点，单条失败不影响整批提交
+			savepoint := fmt.Sprintf("sync_bill_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+
+			bill, failure := h.syncBill(tx, userID, item)
+			if failure != "" {
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+				result.Error = failure
+				response.FailedCount++
+			} else {
+				billResponse := bill.ToResponse()
+				result.ID = bill.ID
+				result.Success = true
+				result.Bill = &billResponse
+				response.SyncedCount++
+				synced = append(synced, *bill)
+			}
+
+			response.Results = append(response.Results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[SyncBills] Transaction error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync bills"})
+		return
+	}
+	h.alerts.CheckBills(userID, synced...)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *BillHandler) syncBill(tx *gorm.DB, userID uint, item models.SyncBillItem) (*models.Bill, string) {
+	legs := billLegs{Type: item.Type, AccountID: item.AccountID, ToAccountID: item.ToAccountID, Currency: item.Currency}
+
+	// 转账没有分类，其余账单允许客户端按名称指定分类
+	if item.Type != models.BillTypeTransfer {
+		var category models.Category
+		categoryQuery := tx.Where("type = ? AND (user_id = ? OR user_id IS NULL)", item.Type, userID)
+		switch {
+		case item.CategoryID != 0:
+			categoryQuery = categoryQuery.Where("id = ?", item.CategoryID)
+		case item.Category != "":
+			categoryQuery = categoryQuery.Where("name = ?", item.Category)
+		default:
+			return nil, "Category is required"
+		}
+		if err := categoryQuery.First(&category).Error; err != nil {
+			return nil, "Invalid category"
+		}
+		legs.CategoryID = &category.ID
+	}
+
+	legs, failure := resolveBillLegs(tx, userID, legs)
+	if failure != "" {
+		return nil, failure
+	}
+	goalID, err := validateBillGoal(tx, userID, item.GoalID)
+	if err != nil {
+		return nil, "Invalid goal"
+	}
+
+	var bill models.Bill
+	err = tx.Unscoped().Where("user_id = ? AND client_id = ?", userID, item.ClientID).First(&bill).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		currency := legs.Currency
+		if currency == "" {
+			if currency, err = userBaseCurrency(tx, userID); err != nil {
+				return nil, "Failed to load base currency"
+			}
+		}
+
+		clientID := item.ClientID
+		bill = models.Bill{
+			UserID:      userID,
+			ClientID:    &clientID,
+			Type:        item.Type,
+			Amount:      item.Amount,
+			Currency:    currency,
+			CategoryID:  legs.CategoryID,
+			AccountID:   legs.AccountID,
+			ToAccountID: legs.ToAccountID,
+			GoalID:      goalID,
+			Channel:     item.Channel,
+			Merchant:    item.Merchant,
+			Description: item.Description,
+			BillTime:    item.BillTime,
+		}
+		if bill.BillTime.IsZero() {
+			bill.BillTime = time.Now()
+		}
+		if err := tx.Create(&bill).Error; err != nil {
+			log.Printf("[SyncBills] Create error for client_id %s: %v", item.ClientID, err)
+			return nil, "Failed to create bill"
+		}
+	case err != nil:
+		log.Printf("[SyncBills] Lookup error for client_id %s: %v", item.ClientID, err)
+		return nil, "Failed to load bill"
+	case bill.DeletedAt.Valid:
+		return nil, "Bill has been deleted"
+	case item.Version != 0 && item.Version != bill.Version:
+		return nil, "Bill has been modified on the server"
+	case item.Currency == "" && legs.AccountID != nil && legs.Currency != bill.Currency:
+		// 未指定币种时沿用账单原币种，不能因为改关联账户而悄悄改变金额的币种
+		return nil, "Bill currency must match the account currency"
+	default:
+		updates := map[string]interface{}{
+			"type":          item.Type,
+			"amount_minor":  item.Amount,
+			"category_id":   legs.CategoryID,
+			"account_id":    legs.AccountID,
+			"to_account_id": legs.ToAccountID,
+			"goal_id":       goalID,
+			"channel":       item.Channel,
+			"merchant":      item.Merchant,
+			"description":   item.Description,
+			"version":       gorm.Expr("version + 1"),
+		}
+		if !item.BillTime.IsZero() {
+			updates["bill_time"] = item.BillTime
+		}
+		if legs.Currency != "" {
+			updates["currency"] = legs.Currency
+		}
+		if err := tx.Model(&bill).Updates(updates).Error; err != nil {
+			log.Printf("[SyncBills] Update error for client_id %s: %v", item.ClientID, err)
+			return nil, "Failed to update bill"
+		}
+	}
+
+	if err := tx.Preload("Category").First(&bill, bill.ID).Error; err != nil {
+		return nil, "Failed to load bill details"
+	}
+
+	return &bill, ""
+}
+
+// billLegs 描述账单的类型、分类、币种及资金流经的账户。转账没有分类，必须同时指定不同的转出和转入账户；
+// 关联账户的账单必须使用账户的币种，Currency 为空时取账户币种（未关联账户时仍为空，由调用方取本位币）
+type billLegs struct {
+	Type        string
+	CategoryID  *uint
+	AccountID   *uint
+	ToAccountID *uint
+	Currency    string
+}
+
+func resolveBillLegs(db *gorm.DB, userID uint, legs billLegs) (billLegs, string) {
+	account, err := loadBillAccount(db, userID, legs.AccountID)
+	if err != nil {
+		return legs, "Invalid account"
+	}
+	legs.AccountID = nil
+	if account != nil {
+		legs.AccountID = &account.ID
+		if legs.Currency == "" {
+			legs.Currency = account.Currency
+		}
+		if legs.Currency != account.Currency {
+			return legs, "Bill currency must match the account currency"
+		}
+	}
+
+	if legs.Type == models.BillTypeTransfer {
+		toAccount, err := loadBillAccount(db, userID, legs.ToAccountID)
+		if err != nil {
+			return legs, "Invalid destination account"
+		}
+		if account == nil || toAccount == nil {
+			return legs, "Transfer requires both account_id and to_account_id"
+		}
+		legs.ToAccountID = &toAccount.ID
+		if account.ID == toAccount.ID {
+			return legs, "Transfer accounts must be different"
+		}
+		// 转账只有一个金额，无法表示不同币种账户间的兑换
+		if toAccount.Currency != account.Currency {
+			return legs, "Transfer accounts must use the same currency"
+		}
+		legs.CategoryID = nil
+		return legs, ""
+	}
+
+	legs.ToAccountID = nil
+	if legs.CategoryID == nil || *legs.CategoryID == 0 {
+		return legs, "Category is required"
+	}
+	var category models.Category
+	if err := db.Where("id = ? AND (user_id = ? OR user_id IS NULL)", *legs.CategoryID, userID).First(&category).Error; err != nil {
+		return legs, "Invalid category"
+	}
+	return legs, ""
+}
+
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func (h *BillHandler) checkBillPrecondition(c *gin.Context, bill *models.Bill) bool {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header required"})
+		return false
+	}
+	if ifMatch == "*" {
+		return true
+	}
+
+	// If-Match 只能使用强比较（RFC 9110），弱校验值无法保证与当前版本完全一致
+	etag := bill.ETag()
+	matched := false
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match requires a strong ETag"})
+			return false
+		}
+		if candidate == etag {
+			matched = true
+		}
+	}
+	if matched {
+		return true
+	}
+
+	h.respondBillConflict(c, bill.ID)
+	return false
+}
+
+func (h *BillHandler) respondBillConflict(c *gin.Context, billID uint) {
+	var current models.Bill
+	if err := h.db.Preload("Category").First(&current, billID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		return
+	}
+
+	c.Header("ETag", current.ETag())
+	c.JSON(http.StatusConflict, gin.H{
+		"error": "Bill has been modified by another device",
+		"bill":  current.ToResponse(),
+	})
+}
+
 func (h *BillHandler) GetStatistics(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
@@ -298,39 +659,16 @@ func (h *BillHandler) GetStatistics(c *gin.Context) {
 		endDate = startDate.AddDate(0, 1, 0).Add(-time.Second)
 	}
 
-	type StatResult struct {
-		Type   string  `json:"type"`
-		Total  float64 `json:"total"`
-		Count  int64   `json:"count"`
-	}
-
-	var stats []StatResult
-	if err := h.db.Model(&models.Bill{}).
-		Select("type, SUM(amount) as total, COUNT(*) as count").
-		Where("user_id = ? AND bill_time >= ? AND bill_time <= ?", userID, startDate, endDate).
-		Group("type").
-		Scan(&stats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statistics"})
+	baseCurrency, err := userBaseCurrency(h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
 		return
 	}
 
-	type CategoryStat struct {
-		CategoryID   uint    `json:"category_id"`
-		CategoryName string  `json:"category_name"`
-		Type         string  `json:"type"`
-		Total        float64 `json:"total"`
-		Count        int64   `json:"count"`
-	}
-
-	var categoryStats []CategoryStat
-	if err := h.db.Model(&models.Bill{}).
-		Select("bills.category_id, categories.name as category_name, bills.type, SUM(bills.amount) as total, COUNT(*) as count").
-		Joins("LEFT JOIN categories ON bills.category_id = categories.id").
-		Where("bills.user_id = ? AND bills.bill_time >= ? AND bills.bill_time <= ?", userID, startDate, endDate).
-		Group("bills.category_id, categories.name, bills.type").
-		Order("total DESC").
-		Scan(&categoryStats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category statistics"})
+	stats, err := aggregateBillStatistics(h.db, userID, baseCurrency, startDate, endDate)
+	if err != nil {
+		log.Printf("[GetStatistics] Aggregation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statistics"})
 		return
 	}
 
@@ -340,8 +678,13 @@ func (h *BillHandler) GetStatistics(c *gin.Context) {
 		"month":      month,
 		"start_date": startDate.Format("2006-01-02"),
 		"end_date":   endDate.Format("2006-01-02"),
-		"summary":    stats,
-		"categories": categoryStats,
+		"currency":   baseCurrency,
+		"summary":    stats.Summary,
+		"categories": stats.Categories,
+	}
+	// 缺少汇率的外币账单未计入汇总，提示客户端补录汇率
+	if len(stats.MissingRates) > 0 {
+		result["missing_rates"] = stats.MissingRates
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"finmind-backend/config"
+	"finmind-backend/importer"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+// 预览后暂存的上传文件保留时长，超时后需重新上传
+const importRetention = 24 * time.Hour
+
+type BillImportHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	alerts *BudgetAlerter
+}
+
+func NewBillImportHandler(db *gorm.DB, cfg *config.Config, alerts *BudgetAlerter) *BillImportHandler {
+	return &BillImportHandler{db: db, cfg: cfg, alerts: alerts}
+}
+
+// ImportBills 默认只解析并返回预览（dry_run=true），上传的文件暂存在 UPLOAD_PATH 下，
+// 确认后以 dry_run=false 和 import_id 提交，所有账单在一个事务中写入。
+// 账单的 client_id 优先使用支付平台的交易号，通用 CSV 由文件内容和行号生成，重复导入不会产生重复账单
+func (h *BillImportHandler) ImportBills(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// 为表单中的其他字段预留 1MB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxUploadSize+1<<20)
+
+	var req models.BillImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	location := time.UTC
+	if req.Timezone != "" {
+		if location, err = time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+	}
+	account, err := loadBillAccount(h.db, userID, req.AccountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account"})
+		return
+	}
+	// 导入到账户时文件中的金额都应以账户币种计，未指定币种时默认使用账户币种
+	var accountID *uint
+	accountCurrency := ""
+	if account != nil {
+		accountID = &account.ID
+		accountCurrency = account.Currency
+	}
+	currency := req.Currency
+	if currency == "" {
+		currency = accountCurrency
+	}
+	if currency == "" {
+		if currency, err = userBaseCurrency(h.db, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+			return
+		}
+	}
+
+	data, ok := h.readUpload(c, userID, req.ImportID)
+	if !ok {
+		return
+	}
+
+	var delimiter rune
+	if req.Delimiter != "" {
+		delimiter = []rune(req.Delimiter)[0]
+	}
+	table, err := importer.ReadTable(data, req.Encoding, delimiter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to read file: %v", err)})
+		return
+	}
+
+	var mapping models.BillImportMapping
+	if req.Mapping != "" {
+		if err := json.Unmarshal([]byte(req.Mapping), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping"})
+			return
+		}
+	}
+
+	var parser importer.Importer
+	switch req.Format {
+	case "", "auto":
+		parser = importer.Detect(table)
+	case importer.FormatCSV:
+	default:
+		parser, _ = importer.ByName(req.Format)
+	}
+	var csvMapping *models.BillImportMapping
+	if parser == nil {
+		if req.Mapping == "" {
+			mapping = importer.GuessMapping(table)
+		}
+		csvMapping = &mapping
+		parser = importer.CSV{Mapping: mapping}
+	}
+
+	// 支付宝和微信支付导出的时间为北京时间，未指定时区时不按 UTC 解释
+	if req.Timezone == "" && (parser.Name() == importer.FormatAlipay || parser.Name() == importer.FormatWeChat) {
+		location = chinaStandardTime()
+	}
+
+	parsed, err := parser.Parse(table, location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to parse file: %v", err)})
+		return
+	}
+
+	resolver, err := newCategoryResolver(h.db, userID, mapping, parser)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category mapping"})
+		return
+	}
+	bills, rowErrors := resolveImportedBills(parsed.Bills, parsed.Errors, resolver, currency, accountCurrency)
+	invalidRows := countRows(rowErrors)
+
+	if req.DryRun == nil || *req.DryRun {
+		importID := req.ImportID
+		if importID == "" {
+			if importID, err = h.saveUpload(userID, data); err != nil {
+				log.Printf("[ImportBills] Failed to save upload: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save upload"})
+				return
+			}
+		}
+
+		preview := bills
+		if len(preview) > models.BillImportPreviewLimit {
+			preview = preview[:models.BillImportPreviewLimit]
+		}
+		delimiter := ""
+		if table.Delimiter != 0 {
+			delimiter = string(table.Delimiter)
+		}
+		c.JSON(http.StatusOK, models.BillImportPreview{
+			ImportID:  importID,
+			Format:    parser.Name(),
+			Encoding:  table.Encoding,
+			Delimiter: delimiter,
+			Columns:   parsed.Columns,
+			Mapping:   csvMapping,
+			TotalRows: len(bills) + invalidRows + len(parsed.Ignored),
+			ValidRows: len(bills),
+			Bills:     preview,
+			Errors:    rowErrors,
+			Ignored:   parsed.Ignored,
+		})
+		return
+	}
+
+	if invalidRows > 0 && !req.SkipInvalid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "File contains invalid rows", "errors": rowErrors})
+		return
+	}
+
+	fingerprint := sha256.Sum256(data)
+	var created []models.Bill
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for _, imported := range bills {
+			clientID := fmt.Sprintf("import-%s-%d", hex.EncodeToString(fingerprint[:8]), imported.Row)
+			if imported.Reference != "" {
+				clientID = fmt.Sprintf("%s-%s", parser.Name(), imported.Reference)
+			}
+			bill := models.Bill{
+				UserID:      userID,
+				ClientID:    &clientID,
+				Type:        imported.Type,
+				Amount:      imported.Amount,
+				Currency:    imported.Currency,
+				CategoryID:  imported.CategoryID,
+				AccountID:   accountID,
+				Channel:     imported.Channel,
+				Merchant:    imported.Merchant,
+				Description: imported.Description,
+				BillTime:    imported.Time,
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bill)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, bill)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[ImportBills] Failed to import bills: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import bills"})
+		return
+	}
+
+	if req.ImportID != "" {
+		os.Remove(h.uploadPath(userID, req.ImportID))
+	}
+	if len(created) > 0 {
+		h.alerts.CheckBills(userID, created...)
+	}
+
+	c.JSON(http.StatusCreated, models.BillImportResult{
+		Imported:   len(created),
+		Duplicates: len(bills) - len(created),
+		Skipped:    invalidRows,
+		Ignored:    len(parsed.Ignored),
+		Errors:     rowErrors,
+	})
+}
+
+// readUpload 读取本次上传的 file，或按 import_id 读取预览时暂存的文件
+func (h *BillImportHandler) readUpload(c *gin.Context, userID uint, importID string) ([]byte, bool) {
+	if importID != "" {
+		path := h.uploadPath(userID, importID)
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) > importRetention {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import not found or expired"})
+			return nil, false
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import not found or expired"})
+			return nil, false
+		}
+		return data, true
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return nil, false
+	}
+	if header.Size > h.cfg.MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return nil, false
+	}
+	return data, true
+}
+
+// saveUpload 暂存上传的文件并顺带清理该用户过期的暂存文件
+func (h *BillImportHandler) saveUpload(userID uint, data []byte) (string, error) {
+	dir := filepath.Dir(h.uploadPath(userID, ""))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > importRetention {
+				os.Remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	importID := hex.EncodeToString(buf)
+	if err := os.WriteFile(h.uploadPath(userID, importID), data, 0o600); err != nil {
+		return "", err
+	}
+	return importID, nil
+}
+
+func (h *BillImportHandler) uploadPath(userID uint, importID string) string {
+	return filepath.Join(h.cfg.UploadPath, "imports", fmt.Sprint(userID), importID+".csv")
+}
+
+// categoryResolver 把文件中的分类名称映射为分类 ID，只匹配用户可见且类型一致的分类。
+// 依次尝试 category_map、同名分类、导入格式建议的分类和 mapping 中的默认分类
+type categoryResolver struct {
+	mapping models.BillImportMapping
+	parser  importer.Importer
+	types   map[uint]string
+	byName  map[string]uint
+}
+
+func newCategoryResolver(db *gorm.DB, userID uint, mapping models.BillImportMapping, parser importer.Importer) (*categoryResolver, error) {
+	var categories []models.Category
+	if err := db.Where("user_id = ? OR user_id IS NULL", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	resolver := &categoryResolver{mapping: mapping, parser: parser, types: make(map[uint]string), byName: make(map[string]uint)}
+	for _, category := range categories {
+		resolver.types[category.ID] = category.Type
+		// 用户自定义分类与默认分类重名时优先匹配自定义分类
+		key := category.Type + "|" + strings.ToLower(category.Name)
+		if _, exists := resolver.byName[key]; !exists || category.UserID != nil {
+			resolver.byName[key] = category.ID
+		}
+	}
+
+	for _, id := range mapping.CategoryMap {
+		if _, ok := resolver.types[id]; !ok {
+			return nil, errors.New("invalid category mapping")
+		}
+	}
+	for _, id := range []uint{mapping.DefaultIncomeCategoryID, mapping.DefaultExpenseCategoryID} {
+		if _, ok := resolver.types[id]; id != 0 && !ok {
+			return nil, errors.New("invalid default category")
+		}
+	}
+	return resolver, nil
+}
+
+func (r *categoryResolver) resolve(name, billType string) *uint {
+	if id, ok := r.mapping.CategoryMap[name]; ok && r.types[id] == billType {
+		return &id
+	}
+	if id, ok := r.byName[billType+"|"+strings.ToLower(strings.TrimSpace(name))]; ok {
+		return &id
+	}
+	for _, suggested := range r.parser.CategoryNames(name, billType) {
+		if id, ok := r.byName[billType+"|"+strings.ToLower(suggested)]; ok {
+			return &id
+		}
+	}
+
+	id := r.mapping.DefaultExpenseCategoryID
+	if billType == models.BillTypeIncome {
+		id = r.mapping.DefaultIncomeCategoryID
+	}
+	if id == 0 || r.types[id] != billType {
+		return nil
+	}
+	return &id
+}
+
+// resolveImportedBills 补全分类和币种，无法确定分类、币种与账户不符或字段超长的行移入错误列表
+func resolveImportedBills(bills []models.ImportedBill, rowErrors []models.BillImportError, resolver *categoryResolver, currency, accountCurrency string) ([]models.ImportedBill, []models.BillImportError) {
+	valid := make([]models.ImportedBill, 0, len(bills))
+	for _, bill := range bills {
+		var errs []models.BillImportError
+		if bill.CategoryID = resolver.resolve(bill.Category, bill.Type); bill.CategoryID == nil {
+			errs = append(errs, models.BillImportError{Row: bill.Row, Field: "category", Message: "Unknown category"})
+		}
+		if bill.Currency == "" {
+			bill.Currency = currency
+		}
+		if !isCurrencyCode(bill.Currency) {
+			errs = append(errs, models.BillImportError{Row: bill.Row, Field: "currency", Message: "Invalid currency"})
+		} else if !money.SupportsCurrency(bill.Currency) {
+			errs = append(errs, models.BillImportError{Row: bill.Row, Field: "currency", Message: "Unsupported currency"})
+		} else if accountCurrency != "" && bill.Currency != accountCurrency {
+			errs = append(errs, models.BillImportError{Row: bill.Row, Field: "currency", Message: "Currency does not match the account"})
+		}
+		if len([]rune(bill.Channel)) > 50 {
+			errs = append(errs, models.BillImportError{Row: bill.Row, Field: "channel", Message: "Channel is too long"})
+		}
+
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		valid = append(valid, bill)
+	}
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+	return valid, rowErrors
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// chinaStandardTime 返回 Asia/Shanghai 时区，系统缺少时区数据时使用固定的 UTC+8（该时区没有夏令时）
+func chinaStandardTime() *time.Location {
+	if location, err := time.LoadLocation("Asia/Shanghai"); err == nil {
+		return location
+	}
+	return time.FixedZone("CST", 8*60*60)
+}
+
+func countRows(rowErrors []models.BillImportError) int {
+	rows := make(map[int]bool)
+	for _, rowError := range rowErrors {
+		rows[rowError.Row] = true
+	}
+	return len(rows)
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+var errInvalidBudgetCategory = errors.New("invalid budget category")
+
+type BudgetHandler struct {
+	db *gorm.DB
+}
+
+func NewBudgetHandler(db *gorm.DB) *BudgetHandler {
+	return &BudgetHandler{db: db}
+}
+
+func (h *BudgetHandler) GetBudgets(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budgets, err := h.loadBudgets(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+		return
+	}
+
+	budgetResponses := make([]models.BudgetResponse, len(budgets))
+	for i, budget := range budgets {
+		budgetResponses[i] = budget.ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"budgets": budgetResponses})
+}
+
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budget, ok := h.loadBudget(c, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, budget.ToResponse())
+}
+
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := validateBudgetCategory(h.db, userID, req.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+		return
+	}
+
+	budget := models.Budget{
+		UserID:   userID,
+		Name:     req.Name,
+		Amount:   req.Amount,
+		Period:   req.Period,
+		Rollover: req.Rollover,
+		Category: category,
+	}
+	if budget.Period == "" {
+		budget.Period = models.BudgetPeriodMonthly
+	}
+	budget.Thresholds = models.DefaultBudgetThresholds
+	if len(req.Thresholds) > 0 {
+		budget.SetThresholds(req.Thresholds)
+	}
+	if category != nil {
+		budget.CategoryID = &category.ID
+	}
+
+	if err := h.db.Omit("Category").Create(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create budget"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, budget.ToResponse())
+}
+
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, ok := h.loadBudget(c, userID)
+	if !ok {
+		return
+	}
+
+	// category_id 传 0 表示改为总预算
+	if req.CategoryID != nil {
+		category, err := validateBudgetCategory(h.db, userID, req.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+			return
+		}
+		budget.Category = category
+		budget.CategoryID = nil
+		if category != nil {
+			budget.CategoryID = &category.ID
+		}
+	}
+	if req.Name != "" {
+		budget.Name = req.Name
+	}
+	if req.Amount != 0 {
+		budget.Amount = req.Amount
+	}
+	if req.Period != "" {
+		budget.Period = req.Period
+	}
+	if req.Rollover != nil {
+		budget.Rollover = *req.Rollover
+	}
+	// 传空数组表示关闭提醒
+	if req.Thresholds != nil {
+		budget.SetThresholds(*req.Thresholds)
+	}
+
+	if err := h.db.Omit("Category").Save(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update budget"})
+		return
+	}
+
+	c.JSON(http.StatusOK, budget.ToResponse())
+}
+
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budget, ok := h.loadBudget(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budget"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
+
+// GetBudgetProgress 报告 date（默认今天）所在周期内各预算的支出进度，支出口径与统计接口一致
+func (h *BudgetHandler) GetBudgetProgress(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.BudgetProgressQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	at := now
+	if query.Date != "" {
+		at, _ = time.Parse("2006-01-02", query.Date)
+	}
+
+	budgets, err := h.loadBudgets(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+		return
+	}
+
+	calculator := newBudgetCalculator(h.db, userID, baseCurrency)
+	progress := make([]models.BudgetProgress, 0, len(budgets))
+	for i := range budgets {
+		p, err := calculator.progress(&budgets[i], at, now)
+		if err != nil {
+			log.Printf("[GetBudgetProgress] Aggregation error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate budget progress"})
+			return
+		}
+		progress = append(progress, p)
+	}
+
+	result := gin.H{
+		"date":     at.Format("2006-01-02"),
+		"currency": baseCurrency,
+		"budgets":  progress,
+	}
+	if missingRates := calculator.missingRates(); len(missingRates) > 0 {
+		result["missing_rates"] = missingRates
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *BudgetHandler) loadBudgets(userID uint) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := h.db.Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("user_id = ?", userID).Order("created_at ASC").Find(&budgets).Error
+	return budgets, err
+}
+
+func (h *BudgetHandler) loadBudget(c *gin.Context, userID uint) (models.Budget, bool) {
+	var budget models.Budget
+
+	budgetID, err := middleware.GetUserIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return budget, false
+	}
+
+	if err := h.db.Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return budget, false
+	}
+	return budget, true
+}
+
+// validateBudgetCategory 校验预算分类为当前用户可见的支出分类，categoryID 为空或 0 表示总预算
+func validateBudgetCategory(db *gorm.DB, userID uint, categoryID *uint) (*models.Category, error) {
+	if categoryID == nil || *categoryID == 0 {
+		return nil, nil
+	}
+
+	var category models.Category
+	if err := db.Where("id = ? AND type = ? AND (user_id = ? OR user_id IS NULL)", *categoryID, models.BillTypeExpense, userID).First(&category).Error; err != nil {
+		return nil, errInvalidBudgetCategory
+	}
+	return &category, nil
+}
+
+// budgetCalculator 计算预算进度，同一周期的收支汇总在一次请求内只查询一次
+type budgetCalculator struct {
+	db           *gorm.DB
+	userID       uint
+	baseCurrency string
+	cache        map[[2]int64]*billStatistics
+	missing      map[string]*missingRate
+}
+
+func newBudgetCalculator(db *gorm.DB, userID uint, baseCurrency string) *budgetCalculator {
+	return &budgetCalculator{
+		db:           db,
+		userID:       userID,
+		baseCurrency: baseCurrency,
+		cache:        make(map[[2]int64]*billStatistics),
+		missing:      make(map[string]*missingRate),
+	}
+}
+
+func (bc *budgetCalculator) statistics(start, end time.Time) (*billStatistics, error) {
+	key := [2]int64{start.Unix(), end.Unix()}
+	if stats, ok := bc.cache[key]; ok {
+		return stats, nil
+	}
+
+	stats, err := aggregateBillStatistics(bc.db, bc.userID, bc.baseCurrency, start, end)
+	if err != nil {
+		return nil, err
+	}
+	bc.cache[key] = stats
+	return stats, nil
+}
+
+// spent 返回预算在 at 所在周期内的支出
+func (bc *budgetCalculator) spent(budget *models.Budget, at time.Time) (money.Amount, error) {
+	start, end := budget.PeriodRange(at)
+	stats, err := bc.statistics(start, end)
+	if err != nil {
+		return 0, err
+	}
+	// 同一笔外币账单可能出现在多个预算的周期中，按币种和日期去重
+	for _, m := range stats.MissingRates {
+		if existing, ok := bc.missing[m.Currency+m.Date]; !ok || existing.Count < m.Count {
+			bc.missing[m.Currency+m.Date] = m
+		}
+	}
+	return stats.Total(models.BillTypeExpense, budget.CategoryID), nil
+}
+
+// progress 计算预算在 at 所在周期的进度，now 用于按已过天数线性推算周期末支出。
+// 开启结转时，上一周期未用完的额度（仅上一周期，不逐期累积）计入本期可用额度
+func (bc *budgetCalculator) progress(budget *models.Budget, at, now time.Time) (models.BudgetProgress, error) {
+	start, end := budget.PeriodRange(at)
+	spent, err := bc.spent(budget, at)
+	if err != nil {
+		return models.BudgetProgress{}, err
+	}
+
+	var rolledOver money.Amount
+	if budget.Rollover && budget.CreatedAt.Before(start) {
+		previousSpent, err := bc.spent(budget, start.Add(-time.Second))
+		if err != nil {
+			return models.BudgetProgress{}, err
+		}
+		if unused := budget.Amount - previousSpent; unused > 0 {
+			rolledOver = unused
+		}
+	}
+
+	daysTotal := int(end.Sub(start).Hours()/24) + 1
+	daysElapsed := daysTotal
+	switch {
+	case now.Before(start):
+		daysElapsed = 0
+	case !now.After(end):
+		daysElapsed = int(now.Sub(start).Hours()/24) + 1
+	}
+
+	projected := spent
+	if daysElapsed > 0 && daysElapsed < daysTotal {
+		projected = spent.Mul(float64(daysTotal) / float64(daysElapsed))
+	}
+
+	available := budget.Amount + rolledOver
+	return models.BudgetProgress{
+		Budget:      budget.ToResponse(),
+		PeriodStart: start.Format("2006-01-02"),
+		PeriodEnd:   end.Format("2006-01-02"),
+		RolledOver:  rolledOver,
+		Available:   available,
+		Spent:       spent,
+		Remaining:   available - spent,
+		Percent:     math.Round(float64(spent)/float64(available)*1000) / 10,
+		Projected:   projected,
+		DaysElapsed: daysElapsed,
+		DaysTotal:   daysTotal,
+	}, nil
+}
+
+func (bc *budgetCalculator) missingRates() []*missingRate {
+	var missingRates []*missingRate
+	for _, m := range bc.missing {
+		missingRates = append(missingRates, m)
+	}
+	sort.Slice(missingRates, func(i, j int) bool {
+		if missingRates[i].Date != missingRates[j].Date {
+			return missingRates[i].Date < missingRates[j].Date
+		}
+		return missingRates[i].Currency < missingRates[j].Currency
+	})
+	return missingRates
+}
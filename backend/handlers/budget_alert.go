@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"finmind-backend/models"
+	"finmind-backend/notify"
+)
+
+// BudgetAlerter 在账单写入后检查相关预算的使用比例，越过阈值时记录提醒并通过通知渠道投递
+type BudgetAlerter struct {
+	db       *gorm.DB
+	notifier notify.Notifier
+}
+
+func NewBudgetAlerter(db *gorm.DB, notifier notify.Notifier) *BudgetAlerter {
+	return &BudgetAlerter{db: db, notifier: notifier}
+}
+
+// CheckBills 只检查受这些支出账单影响、且账单落在当前周期内的预算。检查失败只记录日志，不影响账单写入
+func (a *BudgetAlerter) CheckBills(userID uint, bills ...models.Bill) {
+	if a == nil {
+		return
+	}
+	if err := a.check(userID, bills, time.Now().UTC()); err != nil {
+		log.Printf("[BudgetAlerter] Failed to check budgets for user %d: %v", userID, err)
+	}
+}
+
+func (a *BudgetAlerter) check(userID uint, bills []models.Bill, now time.Time) error {
+	var expenses []models.Bill
+	for _, bill := range bills {
+		if bill.Type == models.BillTypeExpense {
+			expenses = append(expenses, bill)
+		}
+	}
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	var budgets []models.Budget
+	if err := a.db.Where("user_id = ? AND thresholds <> ''", userID).Find(&budgets).Error; err != nil {
+		return err
+	}
+
+	var (
+		calculator *budgetCalculator
+		user       *models.User
+	)
+	for i := range budgets {
+		budget := &budgets[i]
+		if !budgetAffectedBy(budget, expenses, now) {
+			continue
+		}
+
+		if calculator == nil {
+			baseCurrency, err := userBaseCurrency(a.db, userID)
+			if err != nil {
+				return err
+			}
+			calculator = newBudgetCalculator(a.db, userID, baseCurrency)
+		}
+		progress, err := calculator.progress(budget, now, now)
+		if err != nil {
+			return err
+		}
+
+		threshold, err := a.recordCrossedThresholds(budget, progress)
+		if err != nil {
+			return err
+		}
+		if threshold == 0 {
+			continue
+		}
+
+		if user == nil {
+			user = &models.User{}
+			if err := a.db.First(user, userID).Error; err != nil {
+				return err
+			}
+		}
+		a.dispatch(*user, budgetAlertNotification(budget, progress, threshold, calculator.baseCurrency))
+	}
+	return nil
+}
+
+func budgetAffectedBy(budget *models.Budget, bills []models.Bill, now time.Time) bool {
+	start, end := budget.PeriodRange(now)
+	for _, bill := range bills {
+		if bill.BillTime.Before(start) || bill.BillTime.After(end) {
+			continue
+		}
+		if budget.CategoryID == nil || (bill.CategoryID != nil && *bill.CategoryID == *budget.CategoryID) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordCrossedThresholds 为已越过且本周期尚未提醒过的阈值写入记录，返回其中最高的阈值（没有则为 0）。
+// 并发写入同一阈值时由唯一索引去重，只有插入成功的一方会发出通知
+func (a *BudgetAlerter) recordCrossedThresholds(budget *models.Budget, progress models.BudgetProgress) (int, error) {
+	highest := 0
+	for _, threshold := range budget.ThresholdList() {
+		// 用整数比较避免百分比取整导致提前或延后触发
+		if int64(progress.Spent)*100 < int64(progress.Available)*int64(threshold) {
+			break
+		}
+
+		alert := models.BudgetAlert{
+			UserID:      budget.UserID,
+			BudgetID:    budget.ID,
+			PeriodStart: progress.PeriodStart,
+			Threshold:   threshold,
+			Spent:       progress.Spent,
+			Available:   progress.Available,
+		}
+		result := a.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected > 0 {
+			highest = threshold
+		}
+	}
+	return highest, nil
+}
+
+// dispatch 异步投递，避免 webhook 或邮件发送拖慢账单接口
+func (a *BudgetAlerter) dispatch(user models.User, notification models.Notification) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := a.notifier.Notify(ctx, user, notification); err != nil {
+			log.Printf("[BudgetAlerter] Failed to notify user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+func budgetAlertNotification(budget *models.Budget, progress models.BudgetProgress, threshold int, currency string) models.Notification {
+	title := fmt.Sprintf("Budget \"%s\" has reached %d%%", budget.Name, threshold)
+	if threshold >= 100 {
+		title = fmt.Sprintf("Budget \"%s\" has been exceeded", budget.Name)
+	}
+
+	return models.Notification{
+		UserID:   budget.UserID,
+		Type:     models.NotificationTypeBudgetThreshold,
+		Title:    title,
+		BudgetID: &budget.ID,
+		Body: fmt.Sprintf("You have spent %s %s of your %s %s %s budget for %s to %s (%.1f%%). Remaining: %s %s.",
+			progress.Spent.Fixed(), currency, progress.Available.Fixed(), currency, budget.Period,
+			progress.PeriodStart, progress.PeriodEnd, progress.Percent, progress.Remaining.Fixed(), currency),
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"finmind-backend/exchange"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+type ExchangeRateHandler struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateHandler(db *gorm.DB) *ExchangeRateHandler {
+	return &ExchangeRateHandler{db: db}
+}
+
+func (h *ExchangeRateHandler) GetExchangeRates(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.ExchangeRatesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := h.db.Where("user_id IN ?", []uint{0, userID})
+	if query.Base != "" {
+		db = db.Where("base = ?", exchange.NormalizeCurrency(query.Base))
+	}
+	if query.Quote != "" {
+		db = db.Where("quote = ?", exchange.NormalizeCurrency(query.Quote))
+	}
+	if query.Date != "" {
+		db = db.Where("date <= ?", query.Date)
+	}
+
+	var rates []models.ExchangeRate
+	if err := db.Order("date DESC, base ASC, quote ASC").Limit(query.Limit).Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rates": rates})
+}
+
+func (h *ExchangeRateHandler) CreateExchangeRate(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 手动录入的汇率只对当前用户生效，同一天重复录入时覆盖
+	rate := models.ExchangeRate{
+		UserID: userID,
+		Base:   exchange.NormalizeCurrency(req.Base),
+		Quote:  exchange.NormalizeCurrency(req.Quote),
+		Date:   req.Date,
+		Rate:   req.Rate,
+		Source: models.ExchangeRateSourceManual,
+	}
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "base"}, {Name: "quote"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rate"})
+		return
+	}
+
+	if err := h.db.Where("user_id = ? AND base = ? AND quote = ? AND date = ?", userID, rate.Base, rate.Quote, rate.Date).
+		First(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+func (h *ExchangeRateHandler) DeleteExchangeRate(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	rateID, err := middleware.GetUserIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	result := h.db.Where("id = ? AND user_id = ?", rateID, userID).Delete(&models.ExchangeRate{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+func (h *ExchangeRateHandler) Convert(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.ConvertCurrencyQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amount, err := money.Parse(query.Amount)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		return
+	}
+
+	date := time.Now()
+	if query.Date != "" {
+		date, _ = time.Parse(models.ExchangeRateDateFormat, query.Date)
+	}
+
+	rate, ok, err := exchange.NewConverter(h.db, userID).Rate(query.From, query.To, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert amount"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   exchange.NormalizeCurrency(query.From),
+		"to":     exchange.NormalizeCurrency(query.To),
+		"date":   date.Format(models.ExchangeRateDateFormat),
+		"rate":   rate,
+		"amount": amount,
+		"result": amount.Mul(rate),
+	})
+}
+
+func userBaseCurrency(db *gorm.DB, userID uint) (string, error) {
+	var user models.User
+	if err := db.Select("id", "base_currency").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	if user.BaseCurrency == "" {
+		return models.DefaultCurrency, nil
+	}
+	return user.BaseCurrency, nil
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/exchange"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/money"
+	"finmind-backend/recurring"
+)
+
+// 参与预测的订阅候选的最低置信度，与订阅识别接口的默认值一致
+const forecastMinConfidence = 0.5
+
+type ForecastHandler struct {
+	db *gorm.DB
+}
+
+func NewForecastHandler(db *gorm.DB) *ForecastHandler {
+	return &ForecastHandler{db: db}
+}
+
+// GetForecast 从明天起按天预测收支和所有账户的合计余额：周期规则和识别出的订阅按计划日期计入，
+// 其余收支按回看期内各分类的日均金额计入。已由周期规则生成或属于订阅的历史账单不参与均值，避免重复计算
+func (h *ForecastHandler) GetForecast(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.ForecastQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+		return
+	}
+
+	now := time.Now().UTC()
+	start := now.Truncate(24*time.Hour).AddDate(0, 0, 1)
+	end := start.AddDate(0, 0, query.Days)
+	f := &forecaster{
+		converter:    exchange.NewConverter(h.db, userID),
+		baseCurrency: baseCurrency,
+		now:          now,
+		missing:      make(map[string]bool),
+	}
+
+	openingBalance, err := f.totalAccountBalance(h.db, userID)
+	if err != nil {
+		log.Printf("[GetForecast] Balance error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+
+	var rules []models.RecurringRule
+	if err := h.db.Where("user_id = ? AND paused = ? AND next_occurrence IS NOT NULL", userID, false).
+		Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring rules"})
+		return
+	}
+
+	candidates, err := detectSubscriptions(h.db, userID, now)
+	if err != nil {
+		log.Printf("[GetForecast] Detection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect subscriptions"})
+		return
+	}
+
+	var items []models.ForecastItem
+	scheduled := make(map[string]bool)
+	for i := range rules {
+		scheduled[recurring.NormalizeMerchant(rules[i].Template.Merchant)+"|"+rules[i].Template.Currency] = true
+		ruleItems, err := f.ruleItems(&rules[i], end)
+		if err != nil {
+			log.Printf("[GetForecast] Rule %d error: %v", rules[i].ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert currency"})
+			return
+		}
+		items = append(items, ruleItems...)
+	}
+
+	// 已手动设置为周期规则的商户不再按订阅重复计入
+	var subscriptionBillIDs []uint
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.Confidence < forecastMinConfidence {
+			continue
+		}
+		subscriptionBillIDs = append(subscriptionBillIDs, candidate.BillIDs...)
+		if scheduled[recurring.NormalizeMerchant(candidate.Merchant)+"|"+candidate.Currency] {
+			continue
+		}
+		subscriptionItems, err := f.subscriptionItems(candidate, end)
+		if err != nil {
+			log.Printf("[GetForecast] Subscription %s error: %v", candidate.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert currency"})
+			return
+		}
+		items = append(items, subscriptionItems...)
+	}
+
+	averages, err := f.categoryAverages(h.db, userID, start.AddDate(0, 0, -query.LookbackDays), start, subscriptionBillIDs)
+	if err != nil {
+		log.Printf("[GetForecast] Average error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate category averages"})
+		return
+	}
+
+	days := make([]models.ForecastDay, query.Days)
+	for i := range days {
+		days[i].Date = start.AddDate(0, 0, i).Format("2006-01-02")
+		days[i].Items = []models.ForecastItem{}
+		for _, average := range averages {
+			if average.Type == models.BillTypeIncome {
+				days[i].BaselineIncome += average.DailyAverage
+			} else {
+				days[i].BaselineExpense += average.DailyAverage
+			}
+		}
+		days[i].Income = days[i].BaselineIncome
+		days[i].Expense = days[i].BaselineExpense
+	}
+
+	// 尚未生成账单的已到期规则计入第一天
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+	for _, item := range items {
+		i := 0
+		if item.Time.After(start) {
+			i = int(item.Time.Sub(start).Hours() / 24)
+		}
+		if item.Type == models.BillTypeIncome {
+			days[i].Income += item.Amount
+		} else {
+			days[i].Expense += item.Amount
+		}
+		days[i].Items = append(days[i].Items, item)
+	}
+
+	response := models.ForecastResponse{
+		Currency:         baseCurrency,
+		StartDate:        days[0].Date,
+		EndDate:          days[len(days)-1].Date,
+		OpeningBalance:   openingBalance,
+		LowestBalance:    openingBalance,
+		LowestBalanceOn:  now.Format("2006-01-02"),
+		CategoryAverages: averages,
+		Days:             days,
+	}
+	balance := openingBalance
+	for i := range days {
+		balance += days[i].Income - days[i].Expense
+		days[i].Balance = balance
+		response.TotalIncome += days[i].Income
+		response.TotalExpense += days[i].Expense
+		if balance < response.LowestBalance {
+			response.LowestBalance = balance
+			response.LowestBalanceOn = days[i].Date
+		}
+	}
+	response.ClosingBalance = balance
+	for currency := range f.missing {
+		response.MissingRates = append(response.MissingRates, currency)
+	}
+	sort.Strings(response.MissingRates)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// forecaster 按当前汇率把计划收支换算为本位币，缺少汇率的币种记入 missing 并跳过
+type forecaster struct {
+	converter    *exchange.Converter
+	baseCurrency string
+	now          time.Time
+	missing      map[string]bool
+}
+
+func (f *forecaster) convert(amount money.Amount, currency string) (money.Amount, bool, error) {
+	converted, ok, err := f.converter.Convert(amount, currency, f.baseCurrency, f.now)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok {
+		f.missing[currency] = true
+	}
+	return converted, ok, nil
+}
+
+// ruleItems 列出规则在 end 之前的所有发生时间，转账只在账户间移动资金，不影响合计余额
+func (f *forecaster) ruleItems(rule *models.RecurringRule, end time.Time) ([]models.ForecastItem, error) {
+	template := rule.Template
+	if template.Type == models.BillTypeTransfer {
+		return nil, nil
+	}
+
+	amount, ok, err := f.convert(template.Amount, template.Currency)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var items []models.ForecastItem
+	simulated := *rule
+	for next := rule.NextOccurrence; next != nil && next.Before(end); next = recurring.NextAfter(&simulated, next) {
+		items = append(items, models.ForecastItem{
+			Source:          models.ForecastSourceRecurringRule,
+			RecurringRuleID: &rule.ID,
+			Name:            rule.Name,
+			Type:            template.Type,
+			Amount:          amount,
+			OriginalAmount:  template.Amount,
+			Currency:        template.Currency,
+			CategoryID:      template.CategoryID,
+			Time:            *next,
+		})
+		simulated.GeneratedCount++
+	}
+	return items, nil
+}
+
+func (f *forecaster) subscriptionItems(candidate *models.SubscriptionCandidate, end time.Time) ([]models.ForecastItem, error) {
+	amount, ok, err := f.convert(candidate.Amount, candidate.Currency)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var items []models.ForecastItem
+	schedule := models.RecurringRule{Frequency: candidate.Frequency, Interval: 1, StartDate: candidate.NextExpected}
+	for next := &candidate.NextExpected; next != nil && next.Before(end); next = recurring.NextAfter(&schedule, next) {
+		items = append(items, models.ForecastItem{
+			Source:         models.ForecastSourceSubscription,
+			SubscriptionID: candidate.ID,
+			Name:           candidate.Merchant,
+			Type:           models.BillTypeExpense,
+			Amount:         amount,
+			OriginalAmount: candidate.Amount,
+			Currency:       candidate.Currency,
+			CategoryID:     candidate.CategoryID,
+			Time:           *next,
+		})
+	}
+	return items, nil
+}
+
+// categoryAverages 计算 [start, end) 内未关联周期规则、也不属于订阅的收支在各分类的日均金额（本位币）
+func (f *forecaster) categoryAverages(db *gorm.DB, userID uint, start, end time.Time, excludedBillIDs []uint) ([]models.ForecastCategoryAverage, error) {
+	query := db.Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Select("id", "type", "amount_minor", "currency", "category_id", "bill_time").
+		Where("user_id = ? AND type <> ? AND recurring_rule_id IS NULL AND bill_time >= ? AND bill_time < ?",
+			userID, models.BillTypeTransfer, start, end)
+	if len(excludedBillIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludedBillIDs)
+	}
+
+	var bills []models.Bill
+	if err := query.Find(&bills).Error; err != nil {
+		return nil, err
+	}
+
+	type categoryKey struct {
+		categoryID uint
+		billType   string
+	}
+	totals := make(map[categoryKey]money.Amount)
+	names := make(map[categoryKey]string)
+	var keys []categoryKey
+	for _, bill := range bills {
+		amount, ok, err := f.converter.Convert(bill.Amount, bill.Currency, f.baseCurrency, bill.BillTime)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			f.missing[bill.Currency] = true
+			continue
+		}
+
+		key := categoryKey{billType: bill.Type}
+		if bill.CategoryID != nil {
+			key.categoryID = *bill.CategoryID
+		}
+		if _, exists := totals[key]; !exists {
+			keys = append(keys, key)
+			names[key] = bill.Category.Name
+		}
+		totals[key] += amount
+	}
+
+	days := end.Sub(start).Hours() / 24
+	averages := make([]models.ForecastCategoryAverage, 0, len(keys))
+	for _, key := range keys {
+		averages = append(averages, models.ForecastCategoryAverage{
+			CategoryID:   key.categoryID,
+			CategoryName: names[key],
+			Type:         key.billType,
+			DailyAverage: money.Amount(math.Round(float64(totals[key]) / days)),
+		})
+	}
+	sort.SliceStable(averages, func(i, j int) bool { return averages[i].DailyAverage > averages[j].DailyAverage })
+	return averages, nil
+}
+
+// totalAccountBalance 返回所有账户当前余额（含期初余额）换算为本位币后之和，缺少汇率的账户不计入
+func (f *forecaster) totalAccountBalance(db *gorm.DB, userID uint) (money.Amount, error) {
+	var accounts []models.Account
+	if err := db.Select("id", "currency", "opening_balance_minor").Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+		return 0, err
+	}
+
+	balances, err := accountBalances(db, userID, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	var total money.Amount
+	for _, account := range accounts {
+		balance, ok, err := f.convert(account.OpeningBalance+balances[account.ID], account.Currency)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			total += balance
+		}
+	}
+	return total, nil
+}
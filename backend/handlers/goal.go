@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/exchange"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+)
+
+// 按平均每月天数折算月份，用于推算所需月存额和预计完成日期
+const daysPerMonth = 365.25 / 12
+
+var errInvalidGoal = errors.New("invalid goal")
+
+type GoalHandler struct {
+	db *gorm.DB
+}
+
+func NewGoalHandler(db *gorm.DB) *GoalHandler {
+	return &GoalHandler{db: db}
+}
+
+func (h *GoalHandler) GetGoals(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	query := h.db.Where("user_id = ?", userID)
+	if c.Query("include_archived") != "true" {
+		query = query.Where("archived = ?", false)
+	}
+
+	var goals []models.Goal
+	if err := query.Order("created_at ASC").Find(&goals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goals"})
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+		return
+	}
+
+	now := time.Now().UTC()
+	converter := exchange.NewConverter(h.db, userID)
+	goalResponses := make([]models.GoalResponse, len(goals))
+	for i := range goals {
+		progress, _, err := goalProgress(h.db, converter, &goals[i], baseCurrency, now)
+		if err != nil {
+			log.Printf("[GetGoals] Progress error for goal %d: %v", goals[i].ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate goal progress"})
+			return
+		}
+		goalResponses[i] = goals[i].ToResponse(progress)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"goals": goalResponses})
+}
+
+func (h *GoalHandler) GetGoal(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goal, ok := h.loadGoal(c, userID)
+	if !ok {
+		return
+	}
+
+	h.respondGoal(c, userID, &goal, http.StatusOK)
+}
+
+func (h *GoalHandler) CreateGoal(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accountID, err := validateBillAccount(h.db, userID, req.AccountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account"})
+		return
+	}
+
+	goal := models.Goal{
+		UserID:       userID,
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		AccountID:    accountID,
+		Icon:         req.Icon,
+		Color:        req.Color,
+	}
+	if req.Deadline != "" {
+		deadline, _ := time.Parse("2006-01-02", req.Deadline)
+		goal.Deadline = &deadline
+	}
+
+	if err := h.db.Create(&goal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create goal"})
+		return
+	}
+
+	h.respondGoal(c, userID, &goal, http.StatusCreated)
+}
+
+func (h *GoalHandler) UpdateGoal(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	goal, ok := h.loadGoal(c, userID)
+	if !ok {
+		return
+	}
+
+	if req.Name != "" {
+		goal.Name = req.Name
+	}
+	if req.TargetAmount != 0 {
+		goal.TargetAmount = req.TargetAmount
+	}
+	// deadline 传空字符串表示取消截止日期，account_id 传 0 表示解除关联账户
+	if req.Deadline != nil {
+		goal.Deadline = nil
+		if *req.Deadline != "" {
+			deadline, err := time.Parse("2006-01-02", *req.Deadline)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deadline"})
+				return
+			}
+			goal.Deadline = &deadline
+		}
+	}
+	if req.AccountID != nil {
+		if goal.AccountID, err = validateBillAccount(h.db, userID, req.AccountID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account"})
+			return
+		}
+	}
+	if req.Icon != "" {
+		goal.Icon = req.Icon
+	}
+	if req.Color != "" {
+		goal.Color = req.Color
+	}
+	if req.Archived != nil {
+		goal.Archived = *req.Archived
+	}
+
+	if err := h.db.Save(&goal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal"})
+		return
+	}
+
+	h.respondGoal(c, userID, &goal, http.StatusOK)
+}
+
+func (h *GoalHandler) DeleteGoal(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goal, ok := h.loadGoal(c, userID)
+	if !ok {
+		return
+	}
+
+	// 账单本身保留，只取消标记；递增版本号让其他设备在下次同步时拿到变更
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Bill{}).Where("user_id = ? AND goal_id = ?", userID, goal.ID).
+			Updates(map[string]interface{}{"goal_id": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&goal).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete goal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Goal deleted successfully"})
+}
+
+func (h *GoalHandler) GetGoalContributions(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goal, ok := h.loadGoal(c, userID)
+	if !ok {
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+		return
+	}
+
+	_, contributions, err := goalProgress(h.db, exchange.NewConverter(h.db, userID), &goal, baseCurrency, time.Now().UTC())
+	if err != nil {
+		log.Printf("[GetGoalContributions] Progress error for goal %d: %v", goal.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contributions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"goal_id": goal.ID, "currency": baseCurrency, "contributions": contributions})
+}
+
+func (h *GoalHandler) respondGoal(c *gin.Context, userID uint, goal *models.Goal, status int) {
+	baseCurrency, err := userBaseCurrency(h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+		return
+	}
+
+	progress, _, err := goalProgress(h.db, exchange.NewConverter(h.db, userID), goal, baseCurrency, time.Now().UTC())
+	if err != nil {
+		log.Printf("[Goal] Progress error for goal %d: %v", goal.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate goal progress"})
+		return
+	}
+
+	c.JSON(status, goal.ToResponse(progress))
+}
+
+func (h *GoalHandler) loadGoal(c *gin.Context, userID uint) (models.Goal, bool) {
+	var goal models.Goal
+
+	goalID, err := middleware.GetUserIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return goal, false
+	}
+
+	if err := h.db.Where("id = ? AND user_id = ?", goalID, userID).First(&goal).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+		return goal, false
+	}
+	return goal, true
+}
+
+// validateBillGoal 校验账单标记的储蓄目标属于当前用户，goalID 为 0 表示不标记
+func validateBillGoal(db *gorm.DB, userID uint, goalID *uint) (*uint, error) {
+	if goalID == nil || *goalID == 0 {
+		return nil, nil
+	}
+
+	var goal models.Goal
+	if err := db.Where("id = ? AND user_id = ?", *goalID, userID).First(&goal).Error; err != nil {
+		return nil, errInvalidGoal
+	}
+	return &goal.ID, nil
+}
+
+// goalProgress 按时间顺序汇总目标的存取记录：标记到目标的账单中支出计为取出，其余计为存入；
+// 关联账户后，未标记的转账按转入 / 转出该账户计为存入 / 取出。外币账单按账单日期的汇率换算为本位币
+func goalProgress(db *gorm.DB, converter *exchange.Converter, goal *models.Goal, baseCurrency string, now time.Time) (models.GoalProgress, []models.GoalContribution, error) {
+	progress := models.GoalProgress{Currency: baseCurrency}
+
+	query := db.Where("user_id = ?", goal.UserID)
+	if goal.AccountID != nil {
+		query = query.Where("goal_id = ? OR (goal_id IS NULL AND type = ? AND (account_id = ? OR to_account_id = ?))",
+			goal.ID, models.BillTypeTransfer, *goal.AccountID, *goal.AccountID)
+	} else {
+		query = query.Where("goal_id = ?", goal.ID)
+	}
+
+	var bills []models.Bill
+	if err := query.Order("bill_time ASC, id ASC").Find(&bills).Error; err != nil {
+		return progress, nil, err
+	}
+
+	contributions := make([]models.GoalContribution, 0, len(bills))
+	var reachedAt *time.Time
+	first := goal.CreatedAt.UTC()
+	for _, bill := range bills {
+		amount, ok, err := converter.Convert(bill.Amount, bill.Currency, baseCurrency, bill.BillTime)
+		if err != nil {
+			return progress, nil, err
+		}
+		if !ok {
+			progress.MissingRates++
+			continue
+		}
+
+		withdrawal := bill.Type == models.BillTypeExpense ||
+			(bill.Type == models.BillTypeTransfer && goal.AccountID != nil && bill.AccountID != nil && *bill.AccountID == *goal.AccountID)
+		if withdrawal {
+			amount = amount.Neg()
+		}
+
+		progress.Saved += amount
+		progress.ContributionCount++
+		if bill.BillTime.Before(first) {
+			first = bill.BillTime.UTC()
+		}
+		if reachedAt == nil && progress.Saved >= goal.TargetAmount {
+			billTime := bill.BillTime
+			reachedAt = &billTime
+		}
+
+		contributions = append(contributions, models.GoalContribution{
+			BillID:    bill.ID,
+			Type:      bill.Type,
+			Amount:    bill.Amount,
+			Currency:  bill.Currency,
+			Converted: amount,
+			Merchant:  bill.Merchant,
+			Tagged:    bill.GoalID != nil,
+			Time:      bill.BillTime,
+		})
+	}
+
+	progress.Completed = progress.Saved >= goal.TargetAmount
+	if !progress.Completed {
+		progress.Remaining = goal.TargetAmount - progress.Saved
+		reachedAt = nil
+	}
+	progress.Percent = math.Round(float64(progress.Saved)/float64(goal.TargetAmount)*1000) / 10
+
+	// 月均净存入按首次存取（或目标创建）至今的时长平均，不足一个月按一个月计
+	months := math.Max(now.Sub(first).Hours()/24/daysPerMonth, 1)
+	if progress.ContributionCount > 0 {
+		progress.MonthlyAverage = progress.Saved.Mul(1 / months)
+	}
+
+	if goal.Deadline != nil && !progress.Completed {
+		progress.MonthsLeft = 1
+		if days := goal.Deadline.Sub(now).Hours() / 24; days > 0 {
+			progress.MonthsLeft = int(math.Max(math.Ceil(days/daysPerMonth), 1))
+		}
+		progress.RequiredMonthly = progress.Remaining.Mul(1 / float64(progress.MonthsLeft))
+	}
+
+	switch {
+	case reachedAt != nil:
+		progress.ProjectedCompletion = reachedAt.Format("2006-01-02")
+	case progress.MonthlyAverage > 0:
+		days := math.Ceil(float64(progress.Remaining) / float64(progress.MonthlyAverage) * daysPerMonth)
+		progress.ProjectedCompletion = now.AddDate(0, 0, int(days)).Format("2006-01-02")
+	}
+
+	if goal.Deadline != nil {
+		onTrack := progress.Completed
+		if !onTrack && progress.ProjectedCompletion != "" {
+			onTrack = progress.ProjectedCompletion <= goal.Deadline.Format("2006-01-02")
+		}
+		progress.OnTrack = &onTrack
+	}
+
+	return progress, contributions, nil
+}
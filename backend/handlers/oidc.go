@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/oidc"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	errOIDCEmailMissing  = errors.New("identity provider did not return an email")
+	errOIDCEmailConflict = errors.New("email already registered")
+	errLastIdentity      = errors.New("last sign-in method")
+)
+
+type OIDCHandler struct {
+	auth      *AuthHandler
+	providers map[string]*oidc.Provider
+}
+
+func NewOIDCHandler(auth *AuthHandler, providers map[string]*oidc.Provider) *OIDCHandler {
+	return &OIDCHandler{auth: auth, providers: providers}
+}
+
+// OIDCAuthorizeRequest 中的 code_challenge 由客户端按 S256 从自己保存的 code_verifier 计算，
+// 服务端只保存 challenge，回调时客户端必须提交对应的 verifier，截获重定向的第三方无法完成登录
+type OIDCAuthorizeRequest struct {
+	CodeChallenge       string `form:"code_challenge" binding:"required,len=43"`
+	CodeChallengeMethod string `form:"code_challenge_method" binding:"omitempty,eq=S256"`
+}
+
+type OIDCCallbackRequest struct {
+	Code         string `json:"code" form:"code" binding:"required"`
+	State        string `json:"state" form:"state" binding:"required"`
+	CodeVerifier string `json:"code_verifier" form:"code_verifier" binding:"required,min=43,max=128"`
+}
+
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, gin.H{"providers": names})
+}
+
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	var req OIDCAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, req.CodeChallenge)
+	if err != nil {
+		log.Printf("[OIDC] Failed to build authorization URL for %s: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+
+	if err := h.auth.db.Create(&models.OIDCAuthState{
+		Provider:      provider.Name(),
+		StateHash:     hashToken(state),
+		Nonce:         nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oidcStateTTL),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authorization_url": authURL,
+		"state":             state,
+	})
+}
+
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	var req OIDCCallbackRequest
+	bind := c.ShouldBindJSON
+	if c.Request.Method == http.MethodGet {
+		bind = c.ShouldBindQuery
+	}
+	if err := bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := h.auth.db
+	now := time.Now()
+	stateHash := hashToken(req.State)
+
+	var authState models.OIDCAuthState
+	err := db.Where("state_hash = ? AND provider = ? AND used_at IS NULL AND expires_at > ?", stateHash, provider.Name(), now).
+		First(&authState).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify state"})
+		return
+	}
+
+	// 只有发起授权的客户端持有 code_verifier，校验不通过时不消耗 state，避免他人借此中断正常登录
+	if subtle.ConstantTimeCompare([]byte(oidc.CodeChallengeS256(req.CodeVerifier)), []byte(authState.CodeChallenge)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code verifier"})
+		return
+	}
+
+	// state 只能使用一次，防止授权码被重放
+	result := db.Model(&models.OIDCAuthState{}).
+		Where("id = ? AND used_at IS NULL", authState.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify state"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+
+	rawIDToken, err := provider.Exchange(c.Request.Context(), req.Code, req.CodeVerifier)
+	if err != nil {
+		log.Printf("[OIDC] Code exchange with %s failed: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to exchange authorization code"})
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(c.Request.Context(), rawIDToken, authState.Nonce)
+	if err != nil {
+		log.Printf("[OIDC] ID token from %s rejected: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	}
+
+	user, err := h.resolveUser(provider.Name(), claims)
+	switch {
+	case errors.Is(err, errOIDCEmailMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Identity provider did not share an email address"})
+		return
+	case errors.Is(err, errOIDCEmailConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists, please login with your password"})
+		return
+	case err != nil:
+		log.Printf("[OIDC] Failed to resolve user for %s: %v", provider.Name(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
+		return
+	}
+
+	if user.TOTPEnabledAt != nil {
+		challenge, err := h.auth.createMFAChallenge(*user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create MFA challenge"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	accessToken, refreshToken, err := h.auth.generateTokens(c, user.ID, user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		User:         user.ToResponse(),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+func (h *OIDCHandler) resolveUser(provider string, claims *oidc.IDTokenClaims) (*models.User, error) {
+	var user models.User
+	now := time.Now()
+
+	err := h.auth.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]interface{}{
+				"email":         claims.Email,
+				"last_login_at": now,
+			}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		email := strings.TrimSpace(claims.Email)
+		if email == "" {
+			return errOIDCEmailMissing
+		}
+
+		err = tx.Where("email = ?", email).First(&user).Error
+		switch {
+		case err == nil:
+			// 仅当双方都确认过该邮箱时才自动关联，防止通过未验证邮箱抢占账号
+			if !claims.IsEmailVerified() || user.EmailVerifiedAt == nil {
+				return errOIDCEmailConflict
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := h.createOIDCUser(tx, &user, email, claims); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (h *OIDCHandler) createOIDCUser(tx *gorm.DB, user *models.User, email string, claims *oidc.IDTokenClaims) error {
+	// 第三方登录的账号没有本地密码，写入随机密码且不记录 password_set_at，用户可通过找回密码设置
+	randomPassword, err := oidc.RandomString()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+
+	*user = models.User{
+		Name:     name,
+		Email:    email,
+		Password: string(hashedPassword),
+		Avatar:   claims.Picture,
+	}
+	if claims.IsEmailVerified() {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	return tx.Create(user).Error
+}
+
+func (h *OIDCHandler) GetIdentities(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var identities []models.UserIdentity
+	if err := h.auth.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+func (h *OIDCHandler) DeleteIdentity(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	identityID, err := middleware.GetUserIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	// 没有设置过密码的用户只能通过第三方登录，不允许解除最后一个关联，避免无法再登录
+	err = h.auth.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.PasswordSetAt == nil {
+			var count int64
+			if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count <= 1 {
+				return errLastIdentity
+			}
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", identityID, userID).Delete(&models.UserIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	case errors.Is(err, errLastIdentity):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot unlink the last sign-in method, please set a password first"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
@@ -0,0 +1,426 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"finmind-backend/config"
+	"finmind-backend/database"
+	"finmind-backend/handlers"
+	"finmind-backend/jwtkeys"
+	"finmind-backend/mailer"
+	"finmind-backend/models"
+	"finmind-backend/notify"
+	"finmind-backend/oidc"
+	"finmind-backend/oidc/oidctest"
+	"finmind-backend/routes"
+	"finmind-backend/totp"
+)
+
+const oidcRedirectURL = "finmind://oidc/callback"
+
+type oidcTestEnv struct {
+	t        *testing.T
+	db       *gorm.DB
+	router   *gin.Engine
+	provider *oidctest.Server
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	provider, err := oidctest.NewServer("finmind-test", "test-secret")
+	if err != nil {
+		t.Fatalf("start mock provider: %v", err)
+	}
+	t.Cleanup(provider.Close)
+
+	dir := t.TempDir()
+	db, err := database.Connect(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := database.SeedData(db); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	cfg := config.Load()
+	cfg.MailDriver = "log"
+	cfg.MailLogPath = filepath.Join(dir, "mail.log")
+	cfg.OIDCProviders = map[string]config.OIDCProviderConfig{
+		"mock": {
+			Issuer:       provider.Issuer(),
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  oidcRedirectURL,
+		},
+	}
+
+	keys, err := jwtkeys.NewManager(db, cfg)
+	if err != nil {
+		t.Fatalf("create key manager: %v", err)
+	}
+
+	mail := mailer.New(cfg)
+	router := gin.New()
+	routes.SetupRoutes(router, db, cfg, keys, mail, handlers.NewBudgetAlerter(db, notify.New(cfg, db, mail)))
+	return &oidcTestEnv{t: t, db: db, router: router, provider: provider}
+}
+
+func (e *oidcTestEnv) request(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	e.t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			e.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+type oidcRedirect struct {
+	code     string
+	state    string
+	verifier string
+}
+
+// login 走完整的授权码流程：从 authorize 接口取得授权地址，由模拟提供方签发授权码并重定向，再调用回调接口
+func (e *oidcTestEnv) login(identity oidctest.Identity) *httptest.ResponseRecorder {
+	e.t.Helper()
+	redirect := e.authorize(identity)
+	return e.callback(redirect.code, redirect.state, redirect.verifier)
+}
+
+// authorize 像客户端一样生成 code_verifier，只把 code_challenge 交给服务端，返回重定向中的授权码和 state
+func (e *oidcTestEnv) authorize(identity oidctest.Identity) oidcRedirect {
+	e.t.Helper()
+	e.provider.SetIdentity(identity)
+
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		e.t.Fatalf("generate verifier: %v", err)
+	}
+	w := e.request(http.MethodGet, "/api/v1/auth/oidc/mock/authorize?code_challenge="+oidc.CodeChallengeS256(verifier), "", nil)
+	if w.Code != http.StatusOK {
+		e.t.Fatalf("authorize: status %d: %s", w.Code, w.Body)
+	}
+	var authorize struct {
+		AuthorizationURL string `json:"authorization_url"`
+		State            string `json:"state"`
+	}
+	decode(e.t, w, &authorize)
+
+	client := e.provider.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authorize.AuthorizationURL)
+	if err != nil {
+		e.t.Fatalf("provider authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		e.t.Fatalf("provider authorize: status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		e.t.Fatalf("parse redirect: %v", err)
+	}
+	if got := location.Query().Get("state"); got != authorize.State {
+		e.t.Fatalf("redirect state = %q, want %q", got, authorize.State)
+	}
+
+	return oidcRedirect{code: location.Query().Get("code"), state: location.Query().Get("state"), verifier: verifier}
+}
+
+func (e *oidcTestEnv) callback(code, state, verifier string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	return e.request(http.MethodPost, "/api/v1/auth/oidc/mock/callback", "", gin.H{
+		"code":          code,
+		"state":         state,
+		"code_verifier": verifier,
+	})
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body, err)
+	}
+}
+
+type authResponse struct {
+	User         models.UserResponse `json:"user"`
+	AccessToken  string              `json:"access_token"`
+	RefreshToken string              `json:"refresh_token"`
+}
+
+func TestOIDCCallbackCreatesUserAndLinksIdentity(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	identity := oidctest.Identity{Subject: "subject-1", Email: "oidc@example.com", EmailVerified: true, Name: "OIDC User"}
+
+	w := env.login(identity)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body)
+	}
+	var auth authResponse
+	decode(t, w, &auth)
+	if auth.AccessToken == "" || auth.RefreshToken == "" {
+		t.Fatalf("callback did not return tokens: %s", w.Body)
+	}
+	if auth.User.Email != identity.Email || !auth.User.EmailVerified || auth.User.HasPassword {
+		t.Fatalf("unexpected user %+v", auth.User)
+	}
+
+	var identities []models.UserIdentity
+	if err := env.db.Where("user_id = ?", auth.User.ID).Find(&identities).Error; err != nil {
+		t.Fatalf("load identities: %v", err)
+	}
+	if len(identities) != 1 || identities[0].Provider != "mock" || identities[0].Subject != identity.Subject {
+		t.Fatalf("unexpected identities %+v", identities)
+	}
+
+	// 签发的访问令牌可以直接访问受保护的接口
+	w = env.request(http.MethodGet, "/api/v1/user/identities", auth.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get identities: status %d: %s", w.Code, w.Body)
+	}
+
+	// 再次登录复用同一用户和身份关联
+	w = env.login(identity)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second callback: status %d: %s", w.Code, w.Body)
+	}
+	var second authResponse
+	decode(t, w, &second)
+	if second.User.ID != auth.User.ID {
+		t.Fatalf("second login created user %d, want %d", second.User.ID, auth.User.ID)
+	}
+	var count int64
+	env.db.Model(&models.UserIdentity{}).Where("user_id = ?", auth.User.ID).Count(&count)
+	if count != 1 {
+		t.Fatalf("identity count = %d, want 1", count)
+	}
+	env.db.Model(&models.Session{}).Where("user_id = ?", auth.User.ID).Count(&count)
+	if count != 2 {
+		t.Fatalf("session count = %d, want 2", count)
+	}
+}
+
+func TestOIDCCallbackLinksVerifiedExistingUser(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	w := env.request(http.MethodPost, "/api/v1/auth/register", "", gin.H{
+		"name": "Existing", "email": "existing@example.com", "password": "Passw0rd!23",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: status %d: %s", w.Code, w.Body)
+	}
+	var registered authResponse
+	decode(t, w, &registered)
+
+	// 本地邮箱未验证时不自动关联
+	identity := oidctest.Identity{Subject: "subject-2", Email: "existing@example.com", EmailVerified: true}
+	if w := env.login(identity); w.Code != http.StatusConflict {
+		t.Fatalf("unverified local email: status %d, want 409", w.Code)
+	}
+
+	env.db.Model(&models.User{}).Where("id = ?", registered.User.ID).Update("email_verified_at", gorm.Expr("created_at"))
+	w = env.login(identity)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body)
+	}
+	var auth authResponse
+	decode(t, w, &auth)
+	if auth.User.ID != registered.User.ID {
+		t.Fatalf("linked to user %d, want %d", auth.User.ID, registered.User.ID)
+	}
+}
+
+func TestOIDCCallbackRejectsReplayedState(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	redirect := env.authorize(oidctest.Identity{Subject: "subject-3", Email: "replay@example.com", EmailVerified: true})
+
+	if w := env.callback("unknown-code", redirect.state, redirect.verifier); w.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid code: status %d, want 401", w.Code)
+	}
+	if w := env.callback(redirect.code, redirect.state, redirect.verifier); w.Code != http.StatusBadRequest {
+		t.Fatalf("replayed state: status %d, want 400", w.Code)
+	}
+}
+
+func TestOIDCCallbackRequiresClientVerifier(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	redirect := env.authorize(oidctest.Identity{Subject: "subject-5", Email: "pkce@example.com", EmailVerified: true})
+
+	// 截获重定向的一方拿到了 code 和 state，但没有发起授权的客户端保存的 verifier
+	forged, err := oidc.RandomString()
+	if err != nil {
+		t.Fatalf("generate verifier: %v", err)
+	}
+	if w := env.callback(redirect.code, redirect.state, forged); w.Code != http.StatusBadRequest {
+		t.Fatalf("forged verifier: status %d, want 400: %s", w.Code, w.Body)
+	}
+	if w := env.callback(redirect.code, redirect.state, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing verifier: status %d, want 400", w.Code)
+	}
+
+	// 校验失败不消耗 state，发起授权的客户端仍可完成登录
+	if w := env.callback(redirect.code, redirect.state, redirect.verifier); w.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestOIDCAuthorizeRequiresCodeChallenge(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	if w := env.request(http.MethodGet, "/api/v1/auth/oidc/mock/authorize", "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing challenge: status %d, want 400", w.Code)
+	}
+	path := "/api/v1/auth/oidc/mock/authorize?code_challenge_method=plain&code_challenge=" + oidc.CodeChallengeS256("verifier")
+	if w := env.request(http.MethodGet, path, "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("plain method: status %d, want 400", w.Code)
+	}
+}
+
+func TestDeleteLastIdentityRequiresPassword(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	w := env.login(oidctest.Identity{Subject: "subject-4", Email: "only@example.com", EmailVerified: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body)
+	}
+	var auth authResponse
+	decode(t, w, &auth)
+
+	var identity models.UserIdentity
+	if err := env.db.Where("user_id = ?", auth.User.ID).First(&identity).Error; err != nil {
+		t.Fatalf("load identity: %v", err)
+	}
+	path := fmt.Sprintf("/api/v1/user/identities/%d", identity.ID)
+
+	if w := env.request(http.MethodDelete, path, auth.AccessToken, nil); w.Code != http.StatusConflict {
+		t.Fatalf("unlink last identity: status %d, want 409: %s", w.Code, w.Body)
+	}
+
+	env.db.Model(&models.User{}).Where("id = ?", auth.User.ID).Update("password_set_at", gorm.Expr("created_at"))
+	if w := env.request(http.MethodDelete, path, auth.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("unlink with password: status %d: %s", w.Code, w.Body)
+	}
+}
+
+// expireSignIn 把用户所有会话的登录时间改到很久以前，模拟登录后长时间使用刷新令牌续期
+func (e *oidcTestEnv) expireSignIn(userID uint) {
+	e.t.Helper()
+	if err := e.db.Model(&models.Session{}).Where("user_id = ?", userID).
+		Update("issued_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		e.t.Fatalf("expire sessions: %v", err)
+	}
+}
+
+func TestOIDCUserChangesPasswordAfterRecentSignIn(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	w := env.login(oidctest.Identity{Subject: "subject-6", Email: "nopassword@example.com", EmailVerified: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body)
+	}
+	var auth authResponse
+	decode(t, w, &auth)
+
+	body := gin.H{"new_password": "Newpassw0rd!"}
+	env.expireSignIn(auth.User.ID)
+	if w := env.request(http.MethodPut, "/api/v1/user/password", auth.AccessToken, body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("stale sign-in: status %d, want 401: %s", w.Code, w.Body)
+	}
+
+	env.db.Model(&models.Session{}).Where("user_id = ?", auth.User.ID).Update("issued_at", time.Now())
+	if w := env.request(http.MethodPut, "/api/v1/user/password", auth.AccessToken, body); w.Code != http.StatusOK {
+		t.Fatalf("recent sign-in: status %d: %s", w.Code, w.Body)
+	}
+
+	// 设置密码后恢复为校验当前密码
+	if w := env.request(http.MethodPut, "/api/v1/user/password", auth.AccessToken, body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("missing current password: status %d, want 401", w.Code)
+	}
+	body["current_password"] = "Newpassw0rd!"
+	if w := env.request(http.MethodPut, "/api/v1/user/password", auth.AccessToken, body); w.Code != http.StatusOK {
+		t.Fatalf("current password: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestOIDCUserRequestsDeletionWithTOTP(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	w := env.login(oidctest.Identity{Subject: "subject-7", Email: "totp@example.com", EmailVerified: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body)
+	}
+	var auth authResponse
+	decode(t, w, &auth)
+
+	w = env.request(http.MethodPost, "/api/v1/user/2fa/setup", auth.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("setup 2fa: status %d: %s", w.Code, w.Body)
+	}
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	decode(t, w, &setup)
+	step := totp.Step(time.Now())
+	code, err := totp.CodeAt(setup.Secret, step)
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	if w := env.request(http.MethodPost, "/api/v1/user/2fa/enable", auth.AccessToken, gin.H{"code": code}); w.Code != http.StatusOK {
+		t.Fatalf("enable 2fa: status %d: %s", w.Code, w.Body)
+	}
+
+	env.expireSignIn(auth.User.ID)
+	if w := env.request(http.MethodPost, "/api/v1/user/deletion", auth.AccessToken, gin.H{}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("stale sign-in: status %d, want 401: %s", w.Code, w.Body)
+	}
+	if w := env.request(http.MethodPost, "/api/v1/user/deletion", auth.AccessToken, gin.H{"code": "000000"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid code: status %d, want 401", w.Code)
+	}
+
+	// 启用时已使用当前时间步，取下一步的验证码（在允许的时钟偏差内）
+	code, err = totp.CodeAt(setup.Secret, step+1)
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	w = env.request(http.MethodPost, "/api/v1/user/deletion", auth.AccessToken, gin.H{"code": code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("totp code: status %d: %s", w.Code, w.Body)
+	}
+	var deletion struct {
+		ConfirmationToken string `json:"confirmation_token"`
+	}
+	decode(t, w, &deletion)
+	if deletion.ConfirmationToken == "" {
+		t.Fatalf("missing confirmation token: %s", w.Body)
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"finmind-backend/mailer"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+)
+
+const (
+	passwordResetTTL = 1 * time.Hour
+	// 没有本地密码的用户执行敏感操作时，当前会话须在此时间内登录
+	recentSignInWindow = 10 * time.Minute
+)
+
+var errResetTokenUsed = errors.New("reset token already used")
+
+// ChangePasswordRequest 的 current_password 仅对设置过密码的用户必填，
+// 仅通过第三方登录的用户以最近登录或两步验证码 code 确认身份
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sessionID, _ := middleware.GetSessionID(c)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.PasswordSetAt != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+	} else if !h.verifyRecentSignIn(c, &user, req.Code) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password":        string(hashedPassword),
+			"password_set_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		// 修改密码后注销其他设备上的会话，保留当前会话
+		return revokeSessions(tx, "user_id = ? AND id <> ?", userID, sessionID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+}
+
+// verifyRecentSignIn 用于没有本地密码的用户确认身份：提交了有效的两步验证码，或当前会话在
+// recentSignInWindow 内登录（刷新令牌不改变会话的登录时间）。个人访问令牌没有会话，只能使用验证码
+func (h *AuthHandler) verifyRecentSignIn(c *gin.Context, user *models.User, code string) bool {
+	if code != "" && user.TOTPEnabledAt != nil {
+		valid, err := h.verifySecondFactor(user, code, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return false
+		}
+		if !valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+			return false
+		}
+		return true
+	}
+
+	if sessionID, err := middleware.GetSessionID(c); err == nil {
+		var session models.Session
+		err := h.db.Where("id = ? AND user_id = ?", sessionID, user.ID).First(&session).Error
+		if err == nil && time.Since(session.IssuedAt) <= recentSignInWindow {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Please sign in again to confirm this action"})
+	return false
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 无论邮箱是否存在都返回相同结果，避免泄露注册信息
+	response := gin.H{"message": "If the email is registered, a password reset link has been sent"}
+
+	var user models.User
+	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := newTokenID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate reset token"})
+		return
+	}
+
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(passwordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	if err := h.mailer.Send(passwordResetMessage(h.cfg.PasswordResetURL, user, token)); err != nil {
+		log.Printf("[ForgotPassword] Failed to send reset mail to user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	now := time.Now()
+	var resetToken models.PasswordResetToken
+	if err := h.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.Token), now).
+		First(&resetToken).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenUsed
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", resetToken.UserID).
+			Updates(map[string]interface{}{"password": string(hashedPassword), "password_set_at": now}).Error; err != nil {
+			return err
+		}
+		if err := revokeSessions(tx, "user_id = ?", resetToken.UserID); err != nil {
+			return err
+		}
+		// 通过邮件重置密码意味着账号可能已泄露，一并吊销个人访问令牌
+		return revokePersonalAccessTokens(tx, resetToken.UserID)
+	})
+	if errors.Is(err, errResetTokenUsed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+func passwordResetMessage(baseURL string, user models.User, token string) mailer.Message {
+	link := baseURL + "?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your FinMind password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your FinMind password. "+
+			"Use the link below within %d minutes to choose a new one:\n\n%s\n\n"+
+			"Reset code: %s\n\nIf you did not request this, you can ignore this email.\n",
+			user.Name, int(passwordResetTTL.Minutes()), link, token),
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
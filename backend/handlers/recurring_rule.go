@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/recurring"
+)
+
+// 起始时间早于当前时会立即补生成账单，因此最多往前一年；最多往后十年
+const (
+	recurringMaxBackdate = 366 * 24 * time.Hour
+	recurringMaxLead     = 10 * 366 * 24 * time.Hour
+)
+
+type RecurringRuleHandler struct {
+	db     *gorm.DB
+	alerts *BudgetAlerter
+}
+
+func NewRecurringRuleHandler(db *gorm.DB, alerts *BudgetAlerter) *RecurringRuleHandler {
+	return &RecurringRuleHandler{db: db, alerts: alerts}
+}
+
+func (h *RecurringRuleHandler) GetRecurringRules(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var rules []models.RecurringRule
+	if err := h.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recurring_rules": rules})
+}
+
+func (h *RecurringRuleHandler) GetRecurringRule(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	rule, ok := h.loadRecurringRule(c, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RecurringRuleHandler) CreateRecurringRule(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateRecurringRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, failure := resolveBillTemplate(h.db, userID, models.BillTemplate{
+		Type:        req.Type,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		CategoryID:  optionalID(req.CategoryID),
+		AccountID:   req.AccountID,
+		ToAccountID: req.ToAccountID,
+		GoalID:      req.GoalID,
+		Channel:     req.Channel,
+		Merchant:    req.Merchant,
+		Description: req.Description,
+	})
+	if failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+	if template.Currency == "" {
+		if template.Currency, err = userBaseCurrency(h.db, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load base currency"})
+			return
+		}
+	}
+
+	rule := models.RecurringRule{
+		UserID:     userID,
+		Name:       req.Name,
+		Frequency:  req.Frequency,
+		Interval:   req.Interval,
+		DayOfMonth: req.DayOfMonth,
+		StartDate:  req.StartDate.UTC(),
+		Count:      req.Count,
+		Template:   template,
+	}
+	if rule.Interval == 0 {
+		rule.Interval = 1
+	}
+	if req.EndDate != "" {
+		endDate, _ := time.Parse("2006-01-02", req.EndDate)
+		rule.EndDate = &endDate
+	}
+	if failure := checkRuleDates(&rule, true); failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+	rule.NextOccurrence = recurring.NextAfter(&rule, nil)
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring rule"})
+		return
+	}
+
+	// 起始日期在过去时立即补生成已到期的账单，不必等后台任务下一轮
+	h.materialize(&rule)
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *RecurringRuleHandler) UpdateRecurringRule(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateRecurringRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, ok := h.loadRecurringRule(c, userID)
+	if !ok {
+		return
+	}
+
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+
+	// 在现有模板的基础上合并本次修改后整体校验，account_id、to_account_id、goal_id 传 0 表示解除关联
+	template := rule.Template
+	if req.Type != "" {
+		template.Type = req.Type
+	}
+	if req.Amount != 0 {
+		template.Amount = req.Amount
+	}
+	if req.Currency != "" {
+		template.Currency = req.Currency
+	}
+	if req.CategoryID != 0 {
+		template.CategoryID = &req.CategoryID
+	}
+	if req.AccountID != nil {
+		template.AccountID = req.AccountID
+	}
+	if req.ToAccountID != nil {
+		template.ToAccountID = req.ToAccountID
+	}
+	if req.GoalID != nil {
+		template.GoalID = req.GoalID
+	}
+	if req.Channel != "" {
+		template.Channel = req.Channel
+	}
+	if req.Merchant != "" {
+		template.Merchant = req.Merchant
+	}
+	if req.Description != "" {
+		template.Description = req.Description
+	}
+	template, failure := resolveBillTemplate(h.db, userID, template)
+	if failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+	rule.Template = template
+
+	// 修改周期后从最后一次已生成的时间之后重新推算；day_of_month 传 0 表示沿用起始日期的日，
+	// end_date 传空字符串表示不设截止日期，count 传 0 表示不限次数
+	if req.Frequency != "" {
+		rule.Frequency = req.Frequency
+	}
+	if req.Interval != 0 {
+		rule.Interval = req.Interval
+	}
+	if req.DayOfMonth != nil {
+		rule.DayOfMonth = *req.DayOfMonth
+	}
+	if !req.StartDate.IsZero() {
+		rule.StartDate = req.StartDate.UTC()
+	}
+	if req.EndDate != nil {
+		rule.EndDate = nil
+		if *req.EndDate != "" {
+			endDate, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date"})
+				return
+			}
+			rule.EndDate = &endDate
+		}
+	}
+	if req.Count != nil {
+		rule.Count = *req.Count
+	}
+	if failure := checkRuleDates(&rule, !req.StartDate.IsZero()); failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+	after := rule.LastOccurrence
+
+	// 恢复暂停的规则时跳过暂停期间的发生时间，不补生成
+	if req.Paused != nil {
+		if rule.Paused && !*req.Paused {
+			now := time.Now().UTC()
+			if after == nil || after.Before(now) {
+				after = &now
+			}
+		}
+		rule.Paused = *req.Paused
+	}
+	rule.NextOccurrence = recurring.NextAfter(&rule, after)
+
+	if err := h.db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recurring rule"})
+		return
+	}
+
+	h.materialize(&rule)
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RecurringRuleHandler) DeleteRecurringRule(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	rule, ok := h.loadRecurringRule(c, userID)
+	if !ok {
+		return
+	}
+
+	// 已生成的账单保留，只停止后续生成
+	if err := h.db.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recurring rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring rule deleted successfully"})
+}
+
+func (h *RecurringRuleHandler) PreviewRecurringRule(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.RecurringRulePreviewQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, ok := h.loadRecurringRule(c, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring_rule_id": rule.ID,
+		"paused":            rule.Paused,
+		"occurrences":       recurring.Preview(&rule, query.Limit),
+	})
+}
+
+// materialize 生成规则已到期的账单并检查预算提醒，失败时留给后台任务重试
+func (h *RecurringRuleHandler) materialize(rule *models.RecurringRule) {
+	bills, err := recurring.MaterializeRule(h.db, rule, time.Now().UTC())
+	if err != nil {
+		log.Printf("[RecurringRule] Failed to materialize rule %d: %v", rule.ID, err)
+		return
+	}
+	if len(bills) > 0 {
+		h.alerts.CheckBills(rule.UserID, bills...)
+	}
+}
+
+func (h *RecurringRuleHandler) loadRecurringRule(c *gin.Context, userID uint) (models.RecurringRule, bool) {
+	var rule models.RecurringRule
+
+	ruleID, err := middleware.GetUserIDFromParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring rule ID"})
+		return rule, false
+	}
+
+	if err := h.db.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurring rule not found"})
+		return rule, false
+	}
+	return rule, true
+}
+
+// checkRuleDates 校验截止日期不早于起始日期；startChanged 时还要求起始时间在允许的范围内，
+// 已有规则未修改起始时间时不受此限制
+func checkRuleDates(rule *models.RecurringRule, startChanged bool) string {
+	if startChanged {
+		now := time.Now().UTC()
+		if rule.StartDate.Before(now.Add(-recurringMaxBackdate)) {
+			return "start_date must not be more than one year in the past"
+		}
+		if rule.StartDate.After(now.Add(recurringMaxLead)) {
+			return "start_date must not be more than ten years in the future"
+		}
+	}
+	if rule.EndDate != nil && rule.EndDate.Before(rule.StartDate.Truncate(24*time.Hour)) {
+		return "end_date must not be before start_date"
+	}
+	return ""
+}
+
+// resolveBillTemplate 按创建账单的规则校验模板中的类型、分类、币种、账户与储蓄目标
+func resolveBillTemplate(db *gorm.DB, userID uint, template models.BillTemplate) (models.BillTemplate, string) {
+	legs, failure := resolveBillLegs(db, userID, billLegs{
+		Type:        template.Type,
+		CategoryID:  template.CategoryID,
+		AccountID:   template.AccountID,
+		ToAccountID: template.ToAccountID,
+		Currency:    template.Currency,
+	})
+	if failure != "" {
+		return template, failure
+	}
+	template.CategoryID = legs.CategoryID
+	template.AccountID = legs.AccountID
+	template.ToAccountID = legs.ToAccountID
+	template.Currency = legs.Currency
+
+	goalID, err := validateBillGoal(db, userID, template.GoalID)
+	if err != nil {
+		return template, "Invalid goal"
+	}
+	template.GoalID = goalID
+	return template, ""
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"finmind-backend/exchange"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+type statResult struct {
+	Type  string       `json:"type"`
+	Total money.Amount `json:"total"`
+	Count int64        `json:"count"`
+}
+
+type categoryStat struct {
+	CategoryID   uint         `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Type         string       `json:"type"`
+	Total        money.Amount `json:"total"`
+	Count        int64        `json:"count"`
+}
+
+type missingRate struct {
+	Currency string `json:"currency"`
+	Date     string `json:"date"`
+	Count    int64  `json:"count"`
+}
+
+// billStatistics 是某段时间内按类型和分类汇总的收支（本位币），统计接口和预算进度共用
+type billStatistics struct {
+	Summary      []statResult
+	Categories   []categoryStat
+	MissingRates []*missingRate
+}
+
+// aggregateBillStatistics 汇总 [startDate, endDate] 内的收支：本位币账单直接在 SQL 中求和，
+// 外币账单逐笔按账单日期的汇率换算后并入，缺少汇率的账单记入 MissingRates
+func aggregateBillStatistics(db *gorm.DB, userID uint, baseCurrency string, startDate, endDate time.Time) (*billStatistics, error) {
+	result := &billStatistics{}
+
+	// 转账只是资金在账户间移动，不计入收支统计
+	if err := db.Model(&models.Bill{}).
+		Select("type, SUM(amount_minor) as total, COUNT(*) as count").
+		Where("user_id = ? AND type <> ? AND currency = ? AND bill_time >= ? AND bill_time <= ?", userID, models.BillTypeTransfer, baseCurrency, startDate, endDate).
+		Group("type").
+		Scan(&result.Summary).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&models.Bill{}).
+		Select("bills.category_id, categories.name as category_name, bills.type, SUM(bills.amount_minor) as total, COUNT(*) as count").
+		Joins("LEFT JOIN categories ON bills.category_id = categories.id").
+		Where("bills.user_id = ? AND bills.type <> ? AND bills.currency = ? AND bills.bill_time >= ? AND bills.bill_time <= ?", userID, models.BillTypeTransfer, baseCurrency, startDate, endDate).
+		Group("bills.category_id, categories.name, bills.type").
+		Order("total DESC").
+		Scan(&result.Categories).Error; err != nil {
+		return nil, err
+	}
+
+	var foreignBills []models.Bill
+	if err := db.Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Select("id", "type", "amount_minor", "currency", "category_id", "bill_time").
+		Where("user_id = ? AND type <> ? AND currency <> ? AND bill_time >= ? AND bill_time <= ?", userID, models.BillTypeTransfer, baseCurrency, startDate, endDate).
+		Find(&foreignBills).Error; err != nil {
+		return nil, err
+	}
+
+	converter := exchange.NewConverter(db, userID)
+	missing := make(map[string]*missingRate)
+	for _, bill := range foreignBills {
+		amount, ok, err := converter.Convert(bill.Amount, bill.Currency, baseCurrency, bill.BillTime)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			date := bill.BillTime.UTC().Format(models.ExchangeRateDateFormat)
+			if m, exists := missing[bill.Currency+date]; exists {
+				m.Count++
+			} else {
+				missing[bill.Currency+date] = &missingRate{Currency: bill.Currency, Date: date, Count: 1}
+				result.MissingRates = append(result.MissingRates, missing[bill.Currency+date])
+			}
+			continue
+		}
+
+		var categoryID uint
+		if bill.CategoryID != nil {
+			categoryID = *bill.CategoryID
+		}
+		result.add(bill.Type, categoryID, bill.Category.Name, amount)
+	}
+	if len(foreignBills) > 0 {
+		sort.SliceStable(result.Categories, func(i, j int) bool { return result.Categories[i].Total > result.Categories[j].Total })
+	}
+
+	return result, nil
+}
+
+func (s *billStatistics) add(billType string, categoryID uint, categoryName string, amount money.Amount) {
+	found := false
+	for i := range s.Summary {
+		if s.Summary[i].Type == billType {
+			s.Summary[i].Total += amount
+			s.Summary[i].Count++
+			found = true
+			break
+		}
+	}
+	if !found {
+		s.Summary = append(s.Summary, statResult{Type: billType, Total: amount, Count: 1})
+	}
+
+	for i := range s.Categories {
+		if s.Categories[i].CategoryID == categoryID && s.Categories[i].Type == billType {
+			s.Categories[i].Total += amount
+			s.Categories[i].Count++
+			return
+		}
+	}
+	s.Categories = append(s.Categories, categoryStat{
+		CategoryID:   categoryID,
+		CategoryName: categoryName,
+		Type:         billType,
+		Total:        amount,
+		Count:        1,
+	})
+}
+
+// Total 返回指定类型的合计，categoryID 为 nil 时不区分分类
+func (s *billStatistics) Total(billType string, categoryID *uint) money.Amount {
+	var total money.Amount
+	if categoryID == nil {
+		for _, stat := range s.Summary {
+			if stat.Type == billType {
+				total += stat.Total
+			}
+		}
+		return total
+	}
+
+	for _, stat := range s.Categories {
+		if stat.Type == billType && stat.CategoryID == *categoryID {
+			total += stat.Total
+		}
+	}
+	return total
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/recurring"
+)
+
+type SubscriptionHandler struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionHandler(db *gorm.DB) *SubscriptionHandler {
+	return &SubscriptionHandler{db: db}
+}
+
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.SubscriptionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	candidates, err := detectSubscriptions(h.db, userID, time.Now().UTC())
+	if err != nil {
+		log.Printf("[GetSubscriptions] Detection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect subscriptions"})
+		return
+	}
+
+	subscriptions := []models.SubscriptionCandidate{}
+	for _, candidate := range candidates {
+		if candidate.Confidence >= query.MinConfidence {
+			subscriptions = append(subscriptions, candidate)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+// ConvertSubscription 将候选订阅转为周期账单规则，从下一次预计扣费开始生成账单，
+// 历史扣费账单关联到该规则，之后不再作为候选出现
+func (h *SubscriptionHandler) ConvertSubscription(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ConvertSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	candidates, err := detectSubscriptions(h.db, userID, time.Now().UTC())
+	if err != nil {
+		log.Printf("[ConvertSubscription] Detection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect subscriptions"})
+		return
+	}
+
+	var candidate *models.SubscriptionCandidate
+	for i := range candidates {
+		if candidates[i].ID == c.Param("id") {
+			candidate = &candidates[i]
+			break
+		}
+	}
+	if candidate == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	template, failure := resolveBillTemplate(h.db, userID, models.BillTemplate{
+		Type:       models.BillTypeExpense,
+		Amount:     candidate.Amount,
+		Currency:   candidate.Currency,
+		CategoryID: candidate.CategoryID,
+		AccountID:  candidate.AccountID,
+		Merchant:   candidate.Merchant,
+	})
+	if failure != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": failure})
+		return
+	}
+
+	rule := models.RecurringRule{
+		UserID:    userID,
+		Name:      req.Name,
+		Frequency: candidate.Frequency,
+		Interval:  1,
+		StartDate: candidate.NextExpected,
+		Template:  template,
+	}
+	if rule.Name == "" {
+		rule.Name = candidate.Merchant
+	}
+	rule.NextOccurrence = recurring.NextAfter(&rule, nil)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rule).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Bill{}).Where("user_id = ? AND id IN ?", userID, candidate.BillIDs).
+			Updates(map[string]interface{}{"recurring_rule_id": rule.ID, "version": gorm.Expr("version + 1")}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// detectSubscriptions 只分析回看期内未关联周期规则的支出账单
+func detectSubscriptions(db *gorm.DB, userID uint, now time.Time) ([]models.SubscriptionCandidate, error) {
+	var bills []models.Bill
+	if err := db.Where("user_id = ? AND type = ? AND recurring_rule_id IS NULL AND bill_time >= ?",
+		userID, models.BillTypeExpense, now.Add(-recurring.SubscriptionLookback)).
+		Order("bill_time ASC").Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	return recurring.DetectSubscriptions(bills, now), nil
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+// 变更时间取 updated_at 与 deleted_at 中较晚者，软删除不会刷新 updated_at
+const changeTimeExpr = "CASE WHEN deleted_at IS NOT NULL AND deleted_at > updated_at THEN deleted_at ELSE updated_at END"
+
+// updated_at 在写入时而非事务提交时确定，批量同步、导入等较长的事务可能在读取方越过某个时间点后
+// 才提交更早的变更。只下发早于 serverTime - syncSettleDelay 的变更，游标也不会越过该时间点
+const syncSettleDelay = 30 * time.Second
+
+type SyncHandler struct {
+	db *gorm.DB
+}
+
+func NewSyncHandler(db *gorm.DB) *SyncHandler {
+	return &SyncHandler{db: db}
+}
+
+type syncPosition struct {
+	Time time.Time `json:"t"`
+	ID   uint      `json:"i"`
+}
+
+type syncCursor struct {
+	Bills      syncPosition `json:"b"`
+	Categories syncPosition `json:"c"`
+	Accounts   syncPosition `json:"a"`
+}
+
+func decodeSyncCursor(raw string) (syncCursor, error) {
+	var cursor syncCursor
+	if raw == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+func (cursor syncCursor) encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func changeTime(updatedAt time.Time, deletedAt gorm.DeletedAt) time.Time {
+	if deletedAt.Valid && deletedAt.Time.After(updatedAt) {
+		return deletedAt.Time
+	}
+	return updatedAt
+}
+
+func changeAction(since syncPosition, id uint, createdAt time.Time, deletedAt gorm.DeletedAt) string {
+	switch {
+	case deletedAt.Valid:
+		return models.SyncActionDeleted
+	case createdAt.After(since.Time), createdAt.Equal(since.Time) && id > since.ID:
+		return models.SyncActionCreated
+	default:
+		return models.SyncActionUpdated
+	}
+}
+
+func changesSince(db *gorm.DB, since syncPosition, until time.Time, limit int) *gorm.DB {
+	db = db.Unscoped().Where(changeTimeExpr+" < ?", until)
+	if since.Time.IsZero() {
+		// 首次同步时客户端没有本地数据，无需下发已删除记录
+		db = db.Where("deleted_at IS NULL")
+	} else {
+		db = db.Where("("+changeTimeExpr+" > ?) OR ("+changeTimeExpr+" = ? AND id > ?)", since.Time, since.Time, since.ID)
+	}
+	return db.Order(changeTimeExpr + " ASC, id ASC").Limit(limit + 1)
+}
+
+func (h *SyncHandler) GetChanges(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var query models.SyncChangesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cursor, err := decodeSyncCursor(query.Since)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sync cursor"})
+		return
+	}
+
+	serverTime := time.Now()
+	settled := serverTime.Add(-syncSettleDelay)
+
+	var bills []models.Bill
+	billQuery := h.db.Model(&models.Bill{}).Where("user_id = ?", userID)
+	if err := changesSince(billQuery, cursor.Bills, settled, query.Limit).Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Find(&bills).Error; err != nil {
+		log.Printf("[GetChanges] Bills query error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill changes"})
+		return
+	}
+
+	// 个人访问令牌只下发其权限范围内的数据，未授权部分保持为空且游标不前进
+	var categories []models.Category
+	if middleware.AllowsScope(c, models.ScopeCategoriesRead) {
+		categoryQuery := h.db.Model(&models.Category{}).Where("user_id = ? OR user_id IS NULL", userID)
+		if err := changesSince(categoryQuery, cursor.Categories, settled, query.Limit).Find(&categories).Error; err != nil {
+			log.Printf("[GetChanges] Categories query error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category changes"})
+			return
+		}
+	}
+
+	var accounts []models.Account
+	var balances map[uint]money.Amount
+	if middleware.AllowsScope(c, models.ScopeAccountsRead) {
+		accountQuery := h.db.Model(&models.Account{}).Where("user_id = ?", userID)
+		if err := changesSince(accountQuery, cursor.Accounts, settled, query.Limit).Find(&accounts).Error; err != nil {
+			log.Printf("[GetChanges] Accounts query error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account changes"})
+			return
+		}
+
+		if balances, err = accountBalances(h.db, userID, time.Time{}); err != nil {
+			log.Printf("[GetChanges] Balance query error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+			return
+		}
+	}
+
+	response := models.SyncChangesResponse{
+		Bills:      make([]models.SyncChange[models.BillResponse], 0, len(bills)),
+		Categories: make([]models.SyncChange[models.Category], 0, len(categories)),
+		Accounts:   make([]models.SyncChange[models.AccountResponse], 0, len(accounts)),
+		ServerTime: serverTime,
+	}
+
+	if len(bills) > query.Limit {
+		bills = bills[:query.Limit]
+		response.HasMore = true
+	}
+	if len(categories) > query.Limit {
+		categories = categories[:query.Limit]
+		response.HasMore = true
+	}
+	if len(accounts) > query.Limit {
+		accounts = accounts[:query.Limit]
+		response.HasMore = true
+	}
+
+	next := cursor
+	for _, bill := range bills {
+		changedAt := changeTime(bill.UpdatedAt, bill.DeletedAt)
+		response.Bills = append(response.Bills, models.SyncChange[models.BillResponse]{
+			Action:    changeAction(cursor.Bills, bill.ID, bill.CreatedAt, bill.DeletedAt),
+			ID:        bill.ID,
+			ChangedAt: changedAt,
+			Data:      bill.ToResponse(),
+		})
+		next.Bills = syncPosition{Time: changedAt, ID: bill.ID}
+	}
+	for _, category := range categories {
+		changedAt := changeTime(category.UpdatedAt, category.DeletedAt)
+		response.Categories = append(response.Categories, models.SyncChange[models.Category]{
+			Action:    changeAction(cursor.Categories, category.ID, category.CreatedAt, category.DeletedAt),
+			ID:        category.ID,
+			ChangedAt: changedAt,
+			Data:      category,
+		})
+		next.Categories = syncPosition{Time: changedAt, ID: category.ID}
+	}
+	for _, account := range accounts {
+		changedAt := changeTime(account.UpdatedAt, account.DeletedAt)
+		response.Accounts = append(response.Accounts, models.SyncChange[models.AccountResponse]{
+			Action:    changeAction(cursor.Accounts, account.ID, account.CreatedAt, account.DeletedAt),
+			ID:        account.ID,
+			ChangedAt: changedAt,
+			Data:      account.ToResponse(account.OpeningBalance + balances[account.ID]),
+		})
+		next.Accounts = syncPosition{Time: changedAt, ID: account.ID}
+	}
+	response.Cursor = next.encode()
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"finmind-backend/database"
+	"finmind-backend/models"
+)
+
+const (
+	accountDeletionTokenTTL = 10 * time.Minute
+	exportBatchSize         = 500
+)
+
+// RequestAccountDeletionRequest 的 password 仅对设置过密码的用户必填，其他用户见 verifyRecentSignIn
+// 导出包含已软删除的记录（注销时同样会被清除），deleted_at 为 null 表示未删除
+type exportedCategory struct {
+	models.Category
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type exportedAccount struct {
+	models.AccountResponse
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type exportedBudget struct {
+	models.BudgetResponse
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type exportedGoal struct {
+	models.Goal
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type exportedRecurringRule struct {
+	models.RecurringRule
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type exportedBill struct {
+	models.BillResponse
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type RequestAccountDeletionRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type DeleteAccountRequest struct {
+	ConfirmationToken string `json:"confirmation_token" binding:"required"`
+}
+
+func (h *AuthHandler) RequestAccountDeletion(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req RequestAccountDeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if user.PasswordSetAt != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+			return
+		}
+	} else if !h.verifyRecentSignIn(c, &user, req.Code) {
+		return
+	}
+
+	token, err := newTokenID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate confirmation token"})
+		return
+	}
+
+	expiresAt := time.Now().Add(accountDeletionTokenTTL)
+	if err := h.db.Create(&models.AccountDeletionToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create confirmation token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"confirmation_token": token,
+		"expires_at":         expiresAt,
+		"grace_period_hours": int(h.cfg.AccountDeletionGracePeriod.Hours()),
+	})
+}
+
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	result := h.db.Model(&models.AccountDeletionToken{}).
+		Where("user_id = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", user.ID, hashToken(req.ConfirmationToken), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify confirmation token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation token"})
+		return
+	}
+
+	if h.cfg.AccountDeletionGracePeriod <= 0 {
+		if err := database.PurgeUser(h.db, user.ID); err != nil {
+			log.Printf("[DeleteAccount] Failed to purge user %d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+		return
+	}
+
+	scheduledAt := now.Add(h.cfg.AccountDeletionGracePeriod)
+	if err := h.db.Model(&user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "Account deletion scheduled",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+func (h *AuthHandler) CancelAccountDeletion(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.DeletionScheduledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No account deletion scheduled"})
+		return
+	}
+
+	if err := h.db.Model(&user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+func (h *AuthHandler) ExportData(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("finmind-export-%d-%s.zip", user.ID, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，之后的错误只能记录日志并中断输出
+	archive := zip.NewWriter(c.Writer)
+	if err := h.writeExport(archive, user); err != nil {
+		log.Printf("[ExportData] Failed to export data for user %d: %v", user.ID, err)
+		c.Abort()
+		return
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("[ExportData] Failed to finalize archive for user %d: %v", user.ID, err)
+	}
+}
+
+func (h *AuthHandler) writeExport(archive *zip.Writer, user models.User) error {
+	if err := writeJSONEntry(archive, "profile.json", user.ToResponse()); err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	if err := h.db.Unscoped().Where("user_id = ?", user.ID).Order("id ASC").Find(&categories).Error; err != nil {
+		return err
+	}
+	exportedCategories := make([]exportedCategory, len(categories))
+	for i, category := range categories {
+		exportedCategories[i] = exportedCategory{category, deletedAt(category.DeletedAt)}
+	}
+	if err := writeJSONEntry(archive, "categories.json", exportedCategories); err != nil {
+		return err
+	}
+
+	var accounts []models.Account
+	if err := h.db.Unscoped().Where("user_id = ?", user.ID).Order("id ASC").Find(&accounts).Error; err != nil {
+		return err
+	}
+	balances, err := accountBalances(h.db, user.ID, time.Time{})
+	if err != nil {
+		return err
+	}
+	exportedAccounts := make([]exportedAccount, len(accounts))
+	for i, account := range accounts {
+		exportedAccounts[i] = exportedAccount{account.ToResponse(account.OpeningBalance + balances[account.ID]), deletedAt(account.DeletedAt)}
+	}
+	if err := writeJSONEntry(archive, "accounts.json", exportedAccounts); err != nil {
+		return err
+	}
+
+	var budgets []models.Budget
+	if err := h.db.Unscoped().Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("user_id = ?", user.ID).Order("id ASC").Find(&budgets).Error; err != nil {
+		return err
+	}
+	exportedBudgets := make([]exportedBudget, len(budgets))
+	for i, budget := range budgets {
+		exportedBudgets[i] = exportedBudget{budget.ToResponse(), deletedAt(budget.DeletedAt)}
+	}
+	if err := writeJSONEntry(archive, "budgets.json", exportedBudgets); err != nil {
+		return err
+	}
+
+	var goals []models.Goal
+	if err := h.db.Unscoped().Where("user_id = ?", user.ID).Order("id ASC").Find(&goals).Error; err != nil {
+		return err
+	}
+	exportedGoals := make([]exportedGoal, len(goals))
+	for i, goal := range goals {
+		exportedGoals[i] = exportedGoal{goal, deletedAt(goal.DeletedAt)}
+	}
+	if err := writeJSONEntry(archive, "goals.json", exportedGoals); err != nil {
+		return err
+	}
+
+	var recurringRules []models.RecurringRule
+	if err := h.db.Unscoped().Where("user_id = ?", user.ID).Order("id ASC").Find(&recurringRules).Error; err != nil {
+		return err
+	}
+	exportedRules := make([]exportedRecurringRule, len(recurringRules))
+	for i, rule := range recurringRules {
+		exportedRules[i] = exportedRecurringRule{rule, deletedAt(rule.DeletedAt)}
+	}
+	if err := writeJSONEntry(archive, "recurring_rules.json", exportedRules); err != nil {
+		return err
+	}
+
+	var sessions []models.Session
+	if err := h.db.Where("user_id = ?", user.ID).Order("issued_at ASC").Find(&sessions).Error; err != nil {
+		return err
+	}
+	sessionResponses := make([]models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		sessionResponses[i] = session.ToResponse(0)
+	}
+	if err := writeJSONEntry(archive, "sessions.json", sessionResponses); err != nil {
+		return err
+	}
+
+	// 其余归属于用户的数据按原样导出，令牌只包含元数据，哈希值不会序列化
+	records := []struct {
+		name  string
+		dest  interface{}
+		order string
+	}{
+		{"exchange_rates.json", &[]models.ExchangeRate{}, "date ASC, id ASC"},
+		{"notifications.json", &[]models.Notification{}, "id ASC"},
+		{"budget_alerts.json", &[]models.BudgetAlert{}, "id ASC"},
+		{"identities.json", &[]models.UserIdentity{}, "id ASC"},
+		{"personal_access_tokens.json", &[]models.PersonalAccessToken{}, "id ASC"},
+		{"refresh_tokens.json", &[]models.RefreshToken{}, "created_at ASC"},
+		{"password_reset_tokens.json", &[]models.PasswordResetToken{}, "id ASC"},
+		{"email_verification_tokens.json", &[]models.EmailVerificationToken{}, "id ASC"},
+		{"mfa_challenges.json", &[]models.MFAChallenge{}, "id ASC"},
+		{"recovery_codes.json", &[]models.RecoveryCode{}, "id ASC"},
+		{"account_deletion_tokens.json", &[]models.AccountDeletionToken{}, "id ASC"},
+	}
+	for _, record := range records {
+		if err := h.db.Where("user_id = ?", user.ID).Order(record.order).Find(record.dest).Error; err != nil {
+			return err
+		}
+		if err := writeJSONEntry(archive, record.name, record.dest); err != nil {
+			return err
+		}
+	}
+
+	return h.writeBillsExport(archive, user.ID)
+}
+
+func (h *AuthHandler) writeBillsExport(archive *zip.Writer, userID uint) error {
+	jsonFile, err := archive.Create("bills.json")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(jsonFile, "["); err != nil {
+		return err
+	}
+	first := true
+	if err := h.eachExportBill(userID, func(bill models.Bill) error {
+		data, err := json.Marshal(exportedBill{bill.ToResponse(), deletedAt(bill.DeletedAt)})
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(jsonFile, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = jsonFile.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(jsonFile, "]"); err != nil {
+		return err
+	}
+
+	// zip 同一时间只能写入一个文件，CSV 单独再遍历一次
+	csvFile, err := archive.Create("bills.csv")
+	if err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(csvFile)
+	if err := csvWriter.Write([]string{"id", "type", "amount", "currency", "category", "account_id", "to_account_id", "goal_id", "channel", "merchant", "description", "bill_time", "created_at", "updated_at", "deleted_at"}); err != nil {
+		return err
+	}
+	if err := h.eachExportBill(userID, func(bill models.Bill) error {
+		return csvWriter.Write([]string{
+			strconv.FormatUint(uint64(bill.ID), 10),
+			bill.Type,
+			bill.Amount.Fixed(),
+			bill.Currency,
+			bill.Category.Name,
+			formatOptionalID(bill.AccountID),
+			formatOptionalID(bill.ToAccountID),
+			formatOptionalID(bill.GoalID),
+			bill.Channel,
+			bill.Merchant,
+			bill.Description,
+			bill.BillTime.Format(time.RFC3339),
+			bill.CreatedAt.Format(time.RFC3339),
+			bill.UpdatedAt.Format(time.RFC3339),
+			formatOptionalTime(deletedAt(bill.DeletedAt)),
+		})
+	}); err != nil {
+		return err
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func (h *AuthHandler) eachExportBill(userID uint, fn func(models.Bill) error) error {
+	var bills []models.Bill
+	return h.db.Unscoped().Preload("Category", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("user_id = ?", userID).Order("id ASC").FindInBatches(&bills, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, bill := range bills {
+			if err := fn(bill); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func formatOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func deletedAt(deleted gorm.DeletedAt) *time.Time {
+	if !deleted.Valid {
+		return nil
+	}
+	return &deleted.Time
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, value interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
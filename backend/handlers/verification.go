@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/mailer"
+	"finmind-backend/middleware"
+	"finmind-backend/models"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+var errVerificationTokenUsed = errors.New("verification token already used")
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	var verification models.EmailVerificationToken
+	if err := h.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.Token), now).
+		First(&verification).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+
+	var user models.User
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", verification.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVerificationTokenUsed
+		}
+
+		if err := tx.First(&user, verification.UserID).Error; err != nil {
+			return err
+		}
+		// 令牌签发后邮箱已变更，则令牌失效
+		if user.Email != verification.Email {
+			return errVerificationTokenUsed
+		}
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+			return tx.Model(&user).Update("email_verified_at", now).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errVerificationTokenUsed) || errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+		return
+	}
+
+	if err := h.sendEmailVerification(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+func (h *AuthHandler) sendEmailVerification(user models.User) error {
+	token, err := newTokenID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.EmailVerificationToken{
+			UserID:    user.ID,
+			Email:     user.Email,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(emailVerificationTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(emailVerificationMessage(h.cfg.EmailVerificationURL, user, token))
+}
+
+func emailVerificationMessage(baseURL string, user models.User, token string) mailer.Message {
+	link := baseURL + "?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your FinMind email address",
+		Body: fmt.Sprintf("Hi %s,\n\nWelcome to FinMind! Please confirm your email address "+
+			"within %d hours using the link below:\n\n%s\n\n"+
+			"Verification code: %s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.Name, int(emailVerificationTTL.Hours()), link, token),
+	}
+}
@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"finmind-backend/models"
+)
+
+// Alipay 解析支付宝导出的交易明细（CSV，通常为 GBK 编码）。兼容旧版（交易号、付款时间、成功退款列）
+// 和新版（交易时间、交易分类、交易订单号列）两种表头，表头前后的说明和统计行会被忽略
+type Alipay struct{}
+
+func (Alipay) Name() string { return FormatAlipay }
+
+func (Alipay) Detect(table *Table) bool {
+	_, columns, ok := findHeader(table, "交易对方", "收/支", "交易状态")
+	return ok && column(columns, "交易订单号", "交易号") >= 0
+}
+
+func (Alipay) CategoryNames(source, billType string) []string {
+	return nativeCategoryNames(source, billType)
+}
+
+// Parse 只导入交易成功且计入收支的记录：交易关闭、退款成功及"不计收支"（如余额宝转入转出、还款）的记录
+// 被跳过；旧版明细中部分退款的交易按扣除成功退款后的金额导入
+func (Alipay) Parse(table *Table, location *time.Location) (*Result, error) {
+	headerIndex, columns, ok := findHeader(table, "交易对方", "收/支", "交易状态")
+	if !ok {
+		return nil, errUnrecognizedFormat(FormatAlipay)
+	}
+
+	timeCols := []int{column(columns, "交易时间"), column(columns, "付款时间"), column(columns, "交易创建时间")}
+	amountCol := column(columns, "金额")
+	directionCol := column(columns, "收/支")
+	statusCol := column(columns, "交易状态")
+	merchantCol := column(columns, "交易对方")
+	descriptionCol := column(columns, "商品说明", "商品名称")
+	categoryCol := column(columns, "交易分类")
+	referenceCol := column(columns, "交易订单号", "交易号")
+	refundCol := column(columns, "成功退款")
+	remarkCol := column(columns, "备注")
+
+	result := newResult(table.Rows[headerIndex])
+	for i := headerIndex + 1; i < len(table.Rows); i++ {
+		record := table.Rows[i]
+		if !isTradeNumber(cell(record, referenceCol)) {
+			continue
+		}
+		row := table.Lines[i]
+
+		status := cell(record, statusCol)
+		billType := ParseType(cell(record, directionCol))
+		switch {
+		case strings.Contains(status, "关闭"):
+			result.ignore(row, "Transaction closed")
+			continue
+		case strings.Contains(status, "退款"):
+			result.ignore(row, "Transaction refunded")
+			continue
+		case strings.Contains(status, "失败") || strings.Contains(status, "等待"):
+			result.ignore(row, "Transaction not completed")
+			continue
+		case billType == "":
+			result.ignore(row, "Not counted as income or expense")
+			continue
+		}
+
+		amount, err := ParseAmount(cell(record, amountCol))
+		if err != nil || amount <= 0 {
+			result.fail(row, "amount", "Invalid amount")
+			continue
+		}
+		if refund, err := ParseAmount(cell(record, refundCol)); err == nil && refund > 0 {
+			if amount -= refund; amount <= 0 {
+				result.ignore(row, "Transaction refunded")
+				continue
+			}
+		}
+
+		var timeValue string
+		for _, col := range timeCols {
+			if timeValue = cell(record, col); timeValue != "" {
+				break
+			}
+		}
+		billTime, err := ParseTime(timeValue, "", location)
+		if err != nil {
+			result.fail(row, "time", "Invalid time")
+			continue
+		}
+
+		bill := models.ImportedBill{
+			Row:         row,
+			Type:        billType,
+			Amount:      amount,
+			Category:    cell(record, categoryCol),
+			Merchant:    cell(record, merchantCol),
+			Channel:     "支付宝",
+			Description: joinDescription(cell(record, descriptionCol), cell(record, remarkCol)),
+			Reference:   cell(record, referenceCol),
+			Time:        billTime,
+		}
+		if bill.Merchant == "" || bill.Merchant == "/" {
+			bill.Merchant = bill.Description
+		}
+		result.Bills = append(result.Bills, bill)
+	}
+	return result, nil
+}
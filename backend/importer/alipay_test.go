@@ -0,0 +1,165 @@
+package importer_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"finmind-backend/importer"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+var shanghai = time.FixedZone("CST", 8*60*60)
+
+type wantBill struct {
+	Row       int
+	Type      string
+	Amount    money.Amount
+	Merchant  string
+	Category  string
+	Reference string
+	Time      time.Time
+}
+
+func readCSV(t *testing.T, data []byte) *importer.Table {
+	t.Helper()
+	table, err := importer.ReadTable(data, "", 0)
+	if err != nil {
+		t.Fatalf("read table: %v", err)
+	}
+	return table
+}
+
+func ignored(row int, reason string) models.BillImportError {
+	return models.BillImportError{Row: row, Message: reason}
+}
+
+// checkResult 逐行比较导入结果中的账单、跳过行和错误行
+func checkResult(t *testing.T, result *importer.Result, bills []wantBill, skipped, failed []models.BillImportError) {
+	t.Helper()
+	got := make([]wantBill, len(result.Bills))
+	for i, bill := range result.Bills {
+		got[i] = wantBill{bill.Row, bill.Type, bill.Amount, bill.Merchant, bill.Category, bill.Reference, bill.Time}
+	}
+	if !reflect.DeepEqual(got, bills) {
+		t.Errorf("bills = %+v, want %+v", got, bills)
+	}
+	if !reflect.DeepEqual(result.Ignored, skipped) {
+		t.Errorf("ignored = %+v, want %+v", result.Ignored, skipped)
+	}
+	if !reflect.DeepEqual(result.Errors, failed) {
+		t.Errorf("errors = %+v, want %+v", result.Errors, failed)
+	}
+}
+
+func at(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, shanghai)
+	if err != nil {
+		panic(err)
+	}
+	return t.UTC()
+}
+
+func gbk(t *testing.T, text string) []byte {
+	t.Helper()
+	data, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+	return data
+}
+
+func TestAlipayParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		bills   []wantBill
+		ignored []models.BillImportError
+		errors  []models.BillImportError
+	}{
+		{
+			name: "current export with preamble",
+			data: func(t *testing.T) []byte {
+				return []byte(strings.Join([]string{
+					"------------------------------------------------------------------------------------",
+					"导出信息：",
+					"姓名：张三",
+					"支付宝账户：zhangsan@example.com",
+					"起始时间：[2024-03-01 00:00:00]    终止时间：[2024-03-31 23:59:59]",
+					"",
+					"------------------------支付宝（中国）网络技术有限公司  电子客户回单------------------------",
+					"交易时间,交易分类,交易对方,对方账号,商品说明,收/支,金额,收/付款方式,交易状态,交易订单号,商家订单号,备注,",
+					"2024-03-01 12:00:00,餐饮美食,瑞幸咖啡,luckin***@example.com,生椰拿铁,支出,15.90,花呗,交易成功,2024030122001100001,T001,,",
+					"2024-03-02 09:30:00,工资,某某公司,/,三月工资,收入,\"8,000.00\",余额,交易成功,2024030222001100002,/,奖金,",
+					"2024-03-03 10:00:00,日用百货,超市,/,购物,支出,20.00,余额,交易关闭,2024030322001100003,/,,",
+					"2024-03-04 10:00:00,服饰装扮,服装店,/,外套,支出,299.00,余额,退款成功,2024030422001100004,/,,",
+					"2024-03-05 10:00:00,投资理财,余额宝,/,转入余额宝,不计收支,100.00,余额,交易成功,2024030522001100005,/,,",
+					"2024-03-06 10:00:00,数码电器,电器店,/,耳机,支出,abc,余额,交易成功,2024030622001100006,/,,",
+				}, "\n") + "\n")
+			},
+			bills: []wantBill{
+				{9, models.BillTypeExpense, money.FromMinor(1590), "瑞幸咖啡", "餐饮美食", "2024030122001100001", at("2024-03-01 12:00:00")},
+				{10, models.BillTypeIncome, money.FromMinor(800000), "某某公司", "工资", "2024030222001100002", at("2024-03-02 09:30:00")},
+			},
+			ignored: []models.BillImportError{
+				ignored(11, "Transaction closed"),
+				ignored(12, "Transaction refunded"),
+				ignored(13, "Not counted as income or expense"),
+			},
+			errors: []models.BillImportError{{Row: 14, Field: "amount", Message: "Invalid amount"}},
+		},
+		{
+			name: "legacy gbk export with partial refunds and footer",
+			data: func(t *testing.T) []byte {
+				return gbk(t, strings.Join([]string{
+					"支付宝交易记录明细查询",
+					"账号:[20880000000000000156]",
+					"起始日期:[2019-05-01 00:00:00]    终止日期:[2019-05-31 23:59:59]",
+					"---------------------------------交易记录明细列表------------------------------------",
+					"交易号                  ,商户订单号               ,交易创建时间              ,付款时间                ,最近修改时间              ,交易来源地     ,类型              ,交易对方            ,商品名称                ,金额（元）   ,收/支     ,交易状态    ,服务费（元）   ,成功退款（元）  ,备注                  ,资金状态     ,",
+					"2019050122001100001     ,T1                  ,2019-05-01 08:00:00 ,2019-05-01 08:00:05 ,2019-05-01 08:00:05 ,其他（包括阿里巴巴和外部商家）,即时到账交易          ,早餐店             ,包子                ,12.00   ,支出      ,交易成功    ,0.00     ,0.00     ,                    ,已支出      ,",
+					"2019050222001100002     ,T2                  ,2019-05-02 08:00:00 ,2019-05-02 08:00:05 ,2019-05-03 09:00:00 ,淘宝          ,即时到账交易          ,网店               ,鞋子                ,200.00  ,支出      ,交易成功    ,0.00     ,50.00    ,                    ,已支出      ,",
+					"2019050322001100003     ,T3                  ,2019-05-03 08:00:00 ,2019-05-03 08:00:05 ,2019-05-04 09:00:00 ,淘宝          ,即时到账交易          ,网店               ,帽子                ,30.00   ,支出      ,交易成功    ,0.00     ,30.00    ,                    ,已支出      ,",
+					"2019050422001100004     ,T4                  ,2019-05-04 08:00:00 ,                    ,2019-05-04 08:00:00 ,淘宝          ,即时到账交易          ,网店               ,袜子                ,9.90    ,支出      ,等待付款    ,0.00     ,0.00     ,                    ,            ,",
+					"------------------------------------------------------------------------------------",
+					"共4笔记录",
+					"已收入:0笔,0.00元",
+					"待收入:0笔,0.00元",
+				}, "\n")+"\n")
+			},
+			bills: []wantBill{
+				{6, models.BillTypeExpense, money.FromMinor(1200), "早餐店", "", "2019050122001100001", at("2019-05-01 08:00:05")},
+				{7, models.BillTypeExpense, money.FromMinor(15000), "网店", "", "2019050222001100002", at("2019-05-02 08:00:05")},
+			},
+			ignored: []models.BillImportError{
+				ignored(8, "Transaction refunded"),
+				ignored(9, "Transaction not completed"),
+			},
+			errors: []models.BillImportError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := readCSV(t, tt.data(t))
+			if detected := importer.Detect(table); detected == nil || detected.Name() != importer.FormatAlipay {
+				t.Fatalf("detected %v, want alipay", detected)
+			}
+			result, err := importer.Alipay{}.Parse(table, shanghai)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			checkResult(t, result, tt.bills, tt.ignored, tt.errors)
+		})
+	}
+}
+
+func TestAlipayParseRejectsOtherFormats(t *testing.T) {
+	table := readCSV(t, []byte("日期,金额,备注\n2024-03-01,10.00,午饭\n"))
+	if _, err := (importer.Alipay{}).Parse(table, shanghai); err == nil {
+		t.Fatal("expected error for a file without the alipay header")
+	}
+}
@@ -0,0 +1,40 @@
+package importer
+
+import "finmind-backend/models"
+
+// 支付宝交易分类及微信支付交易类型到默认分类名称的对照，名称与 database.SeedData 中的默认分类一致
+var categoryAliases = map[string]string{
+	"餐饮美食": "Food",
+	"服饰装扮": "Shopping",
+	"日用百货": "Shopping",
+	"家居家装": "Shopping",
+	"数码电器": "Shopping",
+	"运动户外": "Shopping",
+	"美容美发": "Shopping",
+	"母婴亲子": "Shopping",
+	"宠物":   "Shopping",
+	"商户消费": "Shopping",
+	"交通出行": "Transport",
+	"爱车养车": "Transport",
+	"住房物业": "Housing",
+	"充值缴费": "Housing",
+	"生活服务": "Housing",
+	"酒店旅游": "Travel",
+	"文化休闲": "Entertainment",
+	"教育培训": "Education",
+	"医疗健康": "Healthcare",
+	"投资理财": "Investment",
+	"收入":   "Other Income",
+}
+
+// nativeCategoryNames 先按对照表匹配，再落到同类型的"其他"分类，保证专用格式的每笔账单都有分类
+func nativeCategoryNames(source, billType string) []string {
+	fallback := "Other Expense"
+	if billType == models.BillTypeIncome {
+		fallback = "Other Income"
+	}
+	if alias, ok := categoryAliases[source]; ok {
+		return []string{alias, fallback}
+	}
+	return []string{fallback}
+}
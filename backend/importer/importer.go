@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"finmind-backend/models"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatAlipay = "alipay"
+	FormatWeChat = "wechat"
+)
+
+// Importer 把某种格式的账单文件解析为待导入的账单
+type Importer interface {
+	Name() string
+	// Detect 判断表格是否为该格式，通用 CSV 不参与自动识别
+	Detect(table *Table) bool
+	Parse(table *Table, location *time.Location) (*Result, error)
+	// CategoryNames 返回来源分类依次尝试匹配的系统分类名称，没有建议时返回 nil
+	CategoryNames(source, billType string) []string
+}
+
+// Result 中 Errors 为无法解析的行，Ignored 为按规则跳过的行（如已关闭、已退款的交易），两者都不会导入
+type Result struct {
+	Columns []string
+	Bills   []models.ImportedBill
+	Errors  []models.BillImportError
+	Ignored []models.BillImportError
+}
+
+func newResult(columns []string) *Result {
+	return &Result{
+		Columns: columns,
+		Bills:   []models.ImportedBill{},
+		Errors:  []models.BillImportError{},
+		Ignored: []models.BillImportError{},
+	}
+}
+
+func (r *Result) fail(row int, field, message string) {
+	r.Errors = append(r.Errors, models.BillImportError{Row: row, Field: field, Message: message})
+}
+
+func (r *Result) ignore(row int, reason string) {
+	r.Ignored = append(r.Ignored, models.BillImportError{Row: row, Message: reason})
+}
+
+var nativeImporters = []Importer{Alipay{}, WeChat{}}
+
+// Detect 返回能识别该表格的专用格式，都不匹配时返回 nil
+func Detect(table *Table) Importer {
+	for _, importer := range nativeImporters {
+		if importer.Detect(table) {
+			return importer
+		}
+	}
+	return nil
+}
+
+// ByName 返回指定名称的专用格式
+func ByName(name string) (Importer, bool) {
+	for _, importer := range nativeImporters {
+		if importer.Name() == name {
+			return importer, true
+		}
+	}
+	return nil, false
+}
+
+// findHeader 在文件开头查找同时包含 required 各列的表头行，返回其下标及规范化列名到列号的映射
+func findHeader(table *Table, required ...string) (int, map[string]int, bool) {
+	for i := 0; i < len(table.Rows) && i < headerSearchRows; i++ {
+		columns := make(map[string]int)
+		for j, cell := range table.Rows[i] {
+			if name := normalizeHeader(cell); name != "" {
+				if _, exists := columns[name]; !exists {
+					columns[name] = j
+				}
+			}
+		}
+
+		found := true
+		for _, name := range required {
+			if _, ok := columns[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i, columns, true
+		}
+	}
+	return 0, nil, false
+}
+
+// column 返回 names 中第一个存在的列号，都不存在时返回 -1
+func column(columns map[string]int, names ...string) int {
+	for _, name := range names {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+func cell(record []string, col int) string {
+	if col < 0 || col >= len(record) {
+		return ""
+	}
+	return record[col]
+}
+
+// isTradeNumber 判断单元格是否为交易单号（只含字母和数字且至少有一位数字），
+// 用于跳过表格末尾的分隔线、统计和说明行
+func isTradeNumber(value string) bool {
+	hasDigit := false
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
+
+func errUnrecognizedFormat(format string) error {
+	return fmt.Errorf("file is not a recognized %s statement", format)
+}
+
+// joinDescription 合并商品说明和备注，忽略导出文件中表示空值的 "/"
+func joinDescription(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" && part != "/" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, " ")
+}
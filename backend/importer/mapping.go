@@ -0,0 +1,287 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+// 自动识别表头时在前多少行中查找，银行等导出文件常在表头前有几行说明
+const headerSearchRows = 30
+
+// 常见表头名称，比较前统一转为小写并去掉空白和括号中的单位（如 "金额(元)"）
+var columnAliases = map[string][]string{
+	"time":        {"time", "date", "datetime", "transaction date", "交易时间", "交易日期", "记账日期", "时间", "日期"},
+	"amount":      {"amount", "金额", "交易金额", "收支金额"},
+	"type":        {"type", "direction", "income/expense", "收/支", "收支", "收支类型", "类型"},
+	"category":    {"category", "分类", "类别", "交易分类"},
+	"merchant":    {"merchant", "payee", "counterparty", "商户", "商家", "交易对方", "对方", "收款方"},
+	"channel":     {"channel", "payment method", "支付方式", "收/付款方式", "渠道"},
+	"description": {"description", "memo", "note", "remark", "备注", "说明", "商品", "商品说明"},
+	"currency":    {"currency", "币种", "货币"},
+}
+
+// 9999-12-31 对应的 xlsx 日期序列号
+const maxExcelSerial = 2958466
+
+var unitSuffix = regexp.MustCompile(`[(（\[【].*?[)）\]】]`)
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"2006/1/2",
+	"2006.01.02",
+	"20060102",
+	"2006年1月2日 15:04:05",
+	"2006年1月2日",
+}
+
+var incomeWords = []string{"income", "in", "credit", "cr", "收入", "收", "入账"}
+var expenseWords = []string{"expense", "out", "debit", "dr", "支出", "支", "出账"}
+
+func normalizeHeader(header string) string {
+	header = unitSuffix.ReplaceAllString(strings.ToLower(header), "")
+	return strings.Join(strings.Fields(header), " ")
+}
+
+// GuessMapping 在文件开头查找匹配已知表头名称最多的一行作为表头，并据此给出列映射
+func GuessMapping(table *Table) models.BillImportMapping {
+	bestRow, bestMatches := 0, 0
+	var best models.BillImportMapping
+	for i := 0; i < len(table.Rows) && i < headerSearchRows; i++ {
+		mapping, matches := guessColumns(table.Rows[i])
+		if matches > bestMatches {
+			bestRow, bestMatches, best = i, matches, mapping
+		}
+	}
+	best.HeaderRow = table.Lines[bestRow]
+	return best
+}
+
+func guessColumns(header []string) (models.BillImportMapping, int) {
+	var mapping models.BillImportMapping
+	fields := map[string]*string{
+		"time":        &mapping.Time,
+		"amount":      &mapping.Amount,
+		"type":        &mapping.Type,
+		"category":    &mapping.Category,
+		"merchant":    &mapping.Merchant,
+		"channel":     &mapping.Channel,
+		"description": &mapping.Description,
+		"currency":    &mapping.Currency,
+	}
+
+	matches := 0
+	for _, value := range header {
+		name := normalizeHeader(value)
+		for field, aliases := range columnAliases {
+			if *fields[field] != "" {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					*fields[field] = value
+					matches++
+					break
+				}
+			}
+		}
+	}
+	return mapping, matches
+}
+
+// CSV 按用户提交或自动识别的列映射解析通用 CSV 文件
+type CSV struct {
+	Mapping models.BillImportMapping
+}
+
+func (CSV) Name() string { return FormatCSV }
+
+func (CSV) Detect(*Table) bool { return false }
+
+func (CSV) CategoryNames(string, string) []string { return nil }
+
+// Parse 按映射解析表头之后的每一行，空行忽略。未映射类型列时按金额正负判断：负数为支出，正数为收入
+func (p CSV) Parse(table *Table, location *time.Location) (*Result, error) {
+	mapping := p.Mapping
+	headerIndex := 0
+	if mapping.HeaderRow > 0 {
+		var ok bool
+		if headerIndex, ok = table.rowIndex(mapping.HeaderRow); !ok {
+			return nil, fmt.Errorf("header row %d is empty or beyond the end of the file", mapping.HeaderRow)
+		}
+	}
+
+	columns := make(map[string]int)
+	for i, value := range table.Rows[headerIndex] {
+		columns[normalizeHeader(value)] = i
+	}
+	index := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := columns[normalizeHeader(name)]
+		if !ok {
+			return -1, fmt.Errorf("column %q not found", name)
+		}
+		return i, nil
+	}
+
+	if mapping.Time == "" || mapping.Amount == "" {
+		return nil, fmt.Errorf("time and amount columns are required")
+	}
+	var timeCol, amountCol, typeCol, categoryCol, merchantCol, channelCol, descriptionCol, currencyCol int
+	for _, column := range []struct {
+		target *int
+		name   string
+	}{
+		{&timeCol, mapping.Time},
+		{&amountCol, mapping.Amount},
+		{&typeCol, mapping.Type},
+		{&categoryCol, mapping.Category},
+		{&merchantCol, mapping.Merchant},
+		{&channelCol, mapping.Channel},
+		{&descriptionCol, mapping.Description},
+		{&currencyCol, mapping.Currency},
+	} {
+		i, err := index(column.name)
+		if err != nil {
+			return nil, err
+		}
+		*column.target = i
+	}
+
+	result := newResult(table.Rows[headerIndex])
+	for i := headerIndex + 1; i < len(table.Rows); i++ {
+		record := table.Rows[i]
+		if isBlank(record) {
+			continue
+		}
+
+		row := table.Lines[i]
+		bill := models.ImportedBill{
+			Row:         row,
+			Category:    cell(record, categoryCol),
+			Merchant:    cell(record, merchantCol),
+			Channel:     cell(record, channelCol),
+			Description: cell(record, descriptionCol),
+			Currency:    strings.ToUpper(cell(record, currencyCol)),
+		}
+		failed := len(result.Errors)
+
+		billTime, err := ParseTime(cell(record, timeCol), mapping.TimeFormat, location)
+		if err != nil {
+			result.fail(row, "time", "Invalid time")
+		}
+		bill.Time = billTime
+
+		amount, err := ParseAmount(cell(record, amountCol))
+		if err != nil || amount == 0 {
+			result.fail(row, "amount", "Invalid amount")
+		}
+		if typeCol >= 0 {
+			if bill.Type = ParseType(cell(record, typeCol)); bill.Type == "" {
+				result.fail(row, "type", "Unknown type")
+			}
+		} else if amount < 0 {
+			bill.Type = models.BillTypeExpense
+		} else {
+			bill.Type = models.BillTypeIncome
+		}
+		if amount < 0 {
+			amount = amount.Neg()
+		}
+		bill.Amount = amount
+
+		if bill.Merchant == "" {
+			bill.Merchant = bill.Description
+		}
+		if bill.Merchant == "" {
+			result.fail(row, "merchant", "Merchant is required")
+		}
+
+		if len(result.Errors) == failed {
+			result.Bills = append(result.Bills, bill)
+		}
+	}
+	return result, nil
+}
+
+// ParseTime 优先使用指定的格式（Go 时间布局），否则依次尝试常见格式和 xlsx 的日期序列号；
+// 不带时区的时间按 location 解释
+func ParseTime(value, layout string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	layouts := timeLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	// xlsx 中的日期单元格保存为自 1899-12-30 起的天数，小数部分为时刻
+	if serial, err := strconv.ParseFloat(value, 64); layout == "" && err == nil && serial > 0 && serial < maxExcelSerial {
+		days := math.Floor(serial)
+		seconds := math.Round((serial - days) * 24 * 60 * 60)
+		t := time.Date(1899, 12, 30, 0, 0, 0, 0, location).AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// ParseAmount 去掉货币符号、千分位和空白后解析，括号包围的金额视为负数
+func ParseAmount(value string) (money.Amount, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")")
+	value = strings.Trim(value, "()")
+	value = strings.NewReplacer("¥", "", "￥", "", "$", "", "€", "", "£", "", ",", "", " ", "", "元", "").Replace(value)
+
+	amount, err := money.Parse(value)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// ParseType 识别常见的收支标记，无法识别时返回空字符串
+func ParseType(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, word := range incomeWords {
+		if value == word {
+			return models.BillTypeIncome
+		}
+	}
+	for _, word := range expenseWords {
+		if value == word {
+			return models.BillTypeExpense
+		}
+	}
+	return ""
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if cell != "" {
+			return false
+		}
+	}
+	return true
+}
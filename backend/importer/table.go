@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	EncodingUTF8 = "utf-8"
+	EncodingGBK  = "gbk"
+)
+
+var ErrEmptyFile = errors.New("file contains no rows")
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// 自动识别分隔符时的候选，按优先级排列
+var delimiters = []rune{',', '\t', ';', '|'}
+
+// Table 是解码并按分隔符切分后的原始单元格，Rows 包含表头及其之前的说明行，
+// Lines 为每行在文件中的行号（从 1 开始，空行不计入 Rows）。xlsx 文件的 Delimiter 为 0
+type Table struct {
+	Encoding  string
+	Delimiter rune
+	Rows      [][]string
+	Lines     []int
+}
+
+// ReadTable 解码文件并切分为单元格，xlsx 文件读取第一个工作表。encoding 为空时，
+// 不是合法 UTF-8 的文件按 GBK（GB18030）解码；delimiter 为 0 时自动识别
+func ReadTable(data []byte, encoding string, delimiter rune) (*Table, error) {
+	if bytes.HasPrefix(data, zipMagic) {
+		return readXLSX(data)
+	}
+
+	text, encoding, err := decode(data, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if delimiter == 0 {
+		delimiter = detectDelimiter(text)
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	table := &Table{Encoding: encoding, Delimiter: delimiter}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		line, _ := reader.FieldPos(0)
+		table.Rows = append(table.Rows, record)
+		table.Lines = append(table.Lines, line)
+	}
+	if len(table.Rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return table, nil
+}
+
+func decode(data []byte, encoding string) (string, string, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if encoding == "" {
+		encoding = EncodingUTF8
+		if !utf8.Valid(data) {
+			encoding = EncodingGBK
+		}
+	}
+
+	if encoding == EncodingGBK {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", "", err
+		}
+		return string(decoded), encoding, nil
+	}
+	return string(data), encoding, nil
+}
+
+// detectDelimiter 取前几行中出现次数稳定且最多的候选分隔符。说明行通常不含分隔符，
+// 因此只统计出现过分隔符的行
+func detectDelimiter(text string) rune {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 20 {
+		lines = lines[:20]
+	}
+
+	best, bestScore := delimiters[0], 0
+	for _, delimiter := range delimiters {
+		counts := make(map[int]int)
+		for _, line := range lines {
+			if n := strings.Count(line, string(delimiter)); n > 0 {
+				counts[n]++
+			}
+		}
+		// 以分隔符个数相同的行数乘以个数作为得分，列数一致的行越多越可信
+		score := 0
+		for n, lineCount := range counts {
+			if n*lineCount > score {
+				score = n * lineCount
+			}
+		}
+		if score > bestScore {
+			best, bestScore = delimiter, score
+		}
+	}
+	return best
+}
+
+// rowIndex 返回文件第 line 行在 Rows 中的下标
+func (t *Table) rowIndex(line int) (int, bool) {
+	for i, l := range t.Lines {
+		if l == line {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"finmind-backend/models"
+)
+
+// 部分退款的状态形如"已退款(￥5.00)"或"已退款￥5.00"
+var partialRefund = regexp.MustCompile(`[￥¥]\s*([0-9][0-9,]*(?:\.[0-9]+)?)`)
+
+// WeChat 解析微信支付导出的账单明细（CSV 或 xlsx），表头前的说明行会被忽略
+type WeChat struct{}
+
+func (WeChat) Name() string { return FormatWeChat }
+
+func (WeChat) Detect(table *Table) bool {
+	_, _, ok := findHeader(table, "交易单号", "当前状态", "收/支")
+	return ok
+}
+
+func (WeChat) CategoryNames(source, billType string) []string {
+	return nativeCategoryNames(source, billType)
+}
+
+// Parse 跳过"收/支"为"/"的记录（如零钱提现、零钱通转入）、已全额退款或对方已退还的交易以及退款入账记录；
+// 部分退款的交易按扣除退款后的金额导入。微信支付没有消费分类，以交易类型作为来源分类
+func (WeChat) Parse(table *Table, location *time.Location) (*Result, error) {
+	headerIndex, columns, ok := findHeader(table, "交易单号", "当前状态", "收/支")
+	if !ok {
+		return nil, errUnrecognizedFormat(FormatWeChat)
+	}
+
+	timeCol := column(columns, "交易时间")
+	kindCol := column(columns, "交易类型")
+	merchantCol := column(columns, "交易对方")
+	descriptionCol := column(columns, "商品")
+	directionCol := column(columns, "收/支")
+	amountCol := column(columns, "金额")
+	statusCol := column(columns, "当前状态")
+	referenceCol := column(columns, "交易单号")
+	remarkCol := column(columns, "备注")
+
+	result := newResult(table.Rows[headerIndex])
+	for i := headerIndex + 1; i < len(table.Rows); i++ {
+		record := table.Rows[i]
+		if !isTradeNumber(cell(record, referenceCol)) {
+			continue
+		}
+		row := table.Lines[i]
+
+		status := cell(record, statusCol)
+		kind := cell(record, kindCol)
+		billType := ParseType(cell(record, directionCol))
+		switch {
+		case strings.Contains(kind, "退款"):
+			result.ignore(row, "Refund of an earlier transaction")
+			continue
+		case strings.Contains(status, "全额退款") || strings.Contains(status, "已退还"):
+			result.ignore(row, "Transaction refunded")
+			continue
+		case strings.Contains(status, "关闭") || strings.Contains(status, "失败"):
+			result.ignore(row, "Transaction closed")
+			continue
+		case billType == "":
+			result.ignore(row, "Not counted as income or expense")
+			continue
+		}
+
+		amount, err := ParseAmount(cell(record, amountCol))
+		if err != nil || amount <= 0 {
+			result.fail(row, "amount", "Invalid amount")
+			continue
+		}
+		if match := partialRefund.FindStringSubmatch(status); strings.Contains(status, "退款") && match != nil {
+			if refund, err := ParseAmount(match[1]); err == nil {
+				if amount -= refund; amount <= 0 {
+					result.ignore(row, "Transaction refunded")
+					continue
+				}
+			}
+		}
+
+		billTime, err := ParseTime(cell(record, timeCol), "", location)
+		if err != nil {
+			result.fail(row, "time", "Invalid time")
+			continue
+		}
+
+		bill := models.ImportedBill{
+			Row:         row,
+			Type:        billType,
+			Amount:      amount,
+			Category:    kind,
+			Merchant:    cell(record, merchantCol),
+			Channel:     "微信支付",
+			Description: joinDescription(cell(record, descriptionCol), cell(record, remarkCol)),
+			Reference:   cell(record, referenceCol),
+			Time:        billTime,
+		}
+		if bill.Merchant == "" || bill.Merchant == "/" {
+			bill.Merchant = kind
+		}
+		result.Bills = append(result.Bills, bill)
+	}
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package importer_test
+
+import (
+	"strings"
+	"testing"
+
+	"finmind-backend/importer"
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+func TestWeChatParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		bills   []wantBill
+		ignored []models.BillImportError
+		errors  []models.BillImportError
+	}{
+		{
+			name: "preamble, refunds and transfers",
+			lines: []string{
+				"微信支付账单明细,,,,,,,,",
+				"微信昵称：[张三],,,,,,,,",
+				"起始时间：[2024-03-01 00:00:00] 终止时间：[2024-03-31 23:59:59],,,,,,,,",
+				"导出类型：[全部],,,,,,,,",
+				"共7笔记录,,,,,,,,",
+				",,,,,,,,",
+				"----------------------微信支付账单明细列表--------------------,,,,,,,,",
+				"交易时间,交易类型,交易对方,商品,收/支,金额(元),支付方式,当前状态,交易单号,商户单号,备注",
+				"2024-03-01 12:00:00,商户消费,美团,外卖订单,支出,¥30.00,零钱,支付成功,4200001,M1,/",
+				"2024-03-02 12:00:00,商户消费,京东,数据线,支出,¥20.00,零钱,已退款(￥5.00),4200002,M2,/",
+				"2024-03-03 12:00:00,商户消费,京东,耳机,支出,¥99.00,零钱,已全额退款,4200003,M3,/",
+				"2024-03-04 12:00:00,京东-退款,京东,耳机,收入,¥99.00,零钱,已全额退款,4200004,M4,/",
+				"2024-03-05 12:00:00,零钱提现,招商银行,/,/,¥500.00,零钱,提现已到账,4200005,/,/",
+				"2024-03-06 12:00:00,转账,/,/,收入,¥100.00,零钱,已收钱,4200006,/,房租分摊",
+				"2024-03-07 12:00:00,转账,李四,/,支出,¥50.00,零钱,对方已退还,4200007,/,/",
+				"2024-03-08 12:00:00,商户消费,便利店,饮料,支出,¥6.00,零钱,已关闭,4200008,/,/",
+				"2024-03-09 12:00:00,商户消费,京东,充电器,支出,¥10.00,零钱,已退款￥10.00,4200009,/,/",
+				"2024-03-10 12:00:00,商户消费,便利店,零食,支出,¥-,零钱,支付成功,4200010,/,/",
+			},
+			bills: []wantBill{
+				{9, models.BillTypeExpense, money.FromMinor(3000), "美团", "商户消费", "4200001", at("2024-03-01 12:00:00")},
+				{10, models.BillTypeExpense, money.FromMinor(1500), "京东", "商户消费", "4200002", at("2024-03-02 12:00:00")},
+				{14, models.BillTypeIncome, money.FromMinor(10000), "转账", "转账", "4200006", at("2024-03-06 12:00:00")},
+			},
+			ignored: []models.BillImportError{
+				ignored(11, "Transaction refunded"),
+				ignored(12, "Refund of an earlier transaction"),
+				ignored(13, "Not counted as income or expense"),
+				ignored(15, "Transaction refunded"),
+				ignored(16, "Transaction closed"),
+				ignored(17, "Transaction refunded"),
+			},
+			errors: []models.BillImportError{{Row: 18, Field: "amount", Message: "Invalid amount"}},
+		},
+		{
+			name: "header on the first line",
+			lines: []string{
+				"交易时间,交易类型,交易对方,商品,收/支,金额(元),支付方式,当前状态,交易单号,商户单号,备注",
+				"2024-03-01 12:00:00,扫二维码付款,早餐店,/,支出,¥8.50,零钱,已支付,4200011,/,/",
+				"2024-03-01 13:00:00,商户消费,面馆,午饭,支出,¥25.00,零钱,已支付,not a number,/,/",
+			},
+			bills: []wantBill{
+				{2, models.BillTypeExpense, money.FromMinor(850), "早餐店", "扫二维码付款", "4200011", at("2024-03-01 12:00:00")},
+			},
+			ignored: []models.BillImportError{},
+			errors:  []models.BillImportError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := readCSV(t, []byte(strings.Join(tt.lines, "\n")+"\n"))
+			if detected := importer.Detect(table); detected == nil || detected.Name() != importer.FormatWeChat {
+				t.Fatalf("detected %v, want wechat", detected)
+			}
+			result, err := importer.WeChat{}.Parse(table, shanghai)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			checkResult(t, result, tt.bills, tt.ignored, tt.errors)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xlsx 文件是 zip 压缩包，以此判断上传的是表格还是文本
+var zipMagic = []byte("PK\x03\x04")
+
+// xlsx 最多 16384 列（XFD）；行数、单元格数、共享字符串和解压后 XML 大小的上限远超正常账单导出，
+// 用于拒绝构造的超大表格（压缩率极高的 zip 炸弹解压后可达数百 MB）
+const (
+	xlsxMaxColumns           = 16384
+	xlsxMaxRows              = 100000
+	xlsxMaxCells             = 2000000
+	xlsxMaxXMLSize           = 256 << 20
+	xlsxMaxSharedStrings     = xlsxMaxCells
+	xlsxMaxSharedStringsSize = 64 << 20
+	xlsxMaxSharedText        = 32 << 20
+)
+
+var (
+	errNoWorksheet           = errors.New("xlsx file contains no worksheet")
+	errTooManyRows           = fmt.Errorf("xlsx worksheet has more than %d rows", xlsxMaxRows)
+	errTooManyCells          = fmt.Errorf("xlsx worksheet has more than %d cells", xlsxMaxCells)
+	errTooManySharedStrings  = fmt.Errorf("xlsx file has more than %d shared strings", xlsxMaxSharedStrings)
+	errSharedStringsTooLarge = fmt.Errorf("xlsx shared strings exceed %d bytes", xlsxMaxSharedText)
+)
+
+// xlsxRichText 既可能是单个 <t>，也可能是多段带格式的 <r><t>
+type xlsxRichText struct {
+	Text string         `xml:"t"`
+	Runs []xlsxRichText `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxRow struct {
+	Number int `xml:"r,attr"`
+	Cells  []struct {
+		Ref    string       `xml:"r,attr"`
+		Type   string       `xml:"t,attr"`
+		Value  string       `xml:"v"`
+		Inline xlsxRichText `xml:"is"`
+	} `xml:"c"`
+}
+
+// readXLSX 只读取第一个工作表的单元格文本，共享字符串和内联字符串展开为文本，数字保持原样。
+// 工作表按行流式解码，超过行数或单元格数上限时返回错误
+func readXLSX(data []byte) (*Table, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings []string
+	var sheets []*zip.File
+	for _, file := range archive.File {
+		switch {
+		case file.Name == "xl/sharedStrings.xml":
+			if sharedStrings, err = readSharedStrings(file); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(file.Name, "xl/worksheets/sheet") && strings.HasSuffix(file.Name, ".xml"):
+			sheets = append(sheets, file)
+		}
+	}
+	if len(sheets) == 0 {
+		return nil, errNoWorksheet
+	}
+	sort.Slice(sheets, func(i, j int) bool { return sheetNumber(sheets[i].Name) < sheetNumber(sheets[j].Name) })
+
+	reader, err := sheets[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decoder := xml.NewDecoder(io.LimitReader(reader, xlsxMaxXMLSize))
+
+	table := &Table{Encoding: EncodingUTF8}
+	rows, cells := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		if rows++; rows > xlsxMaxRows {
+			return nil, errTooManyRows
+		}
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, err
+		}
+
+		var record []string
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				if column, err = columnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			if column >= xlsxMaxColumns {
+				return nil, fmt.Errorf("xlsx row %d has more than %d columns", row.Number, xlsxMaxColumns)
+			}
+			if column >= len(record) {
+				if cells += column + 1 - len(record); cells > xlsxMaxCells {
+					return nil, errTooManyCells
+				}
+				record = append(record, make([]string, column+1-len(record))...)
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				if index, err := strconv.Atoi(value); err == nil && index >= 0 && index < len(sharedStrings) {
+					value = sharedStrings[index]
+				}
+			case "inlineStr":
+				value = cell.Inline.String()
+			}
+			record[column] = strings.TrimSpace(value)
+		}
+		if isBlank(record) {
+			continue
+		}
+		table.Rows = append(table.Rows, record)
+		table.Lines = append(table.Lines, row.Number)
+	}
+	if len(table.Rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return table, nil
+}
+
+// readSharedStrings 逐个解码共享字符串，超过数量或文本总长度上限时返回错误
+func readSharedStrings(file *zip.File) ([]string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decoder := xml.NewDecoder(io.LimitReader(reader, xlsxMaxSharedStringsSize))
+
+	var items []string
+	size := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "si" {
+			continue
+		}
+
+		if len(items) >= xlsxMaxSharedStrings {
+			return nil, errTooManySharedStrings
+		}
+		var item xlsxRichText
+		if err := decoder.DecodeElement(&item, &start); err != nil {
+			return nil, err
+		}
+		text := item.String()
+		if size += len(text); size > xlsxMaxSharedText {
+			return nil, errSharedStringsTooLarge
+		}
+		items = append(items, text)
+	}
+}
+
+func sheetNumber(name string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "xl/worksheets/sheet"), ".xml"))
+	return n
+}
+
+// columnIndex 把单元格引用（如 "AB12"）的列字母转为从 0 开始的列号，列字母最多 3 位且不超过 XFD
+func columnIndex(ref string) (int, error) {
+	column, letters := 0, 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		if letters++; letters > 3 {
+			return 0, fmt.Errorf("invalid xlsx cell reference %q", ref)
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	if letters == 0 || column > xlsxMaxColumns {
+		return 0, fmt.Errorf("invalid xlsx cell reference %q", ref)
+	}
+	return column - 1, nil
+}
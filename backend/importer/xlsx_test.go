@@ -0,0 +1,136 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"finmind-backend/importer"
+)
+
+type xlsxPart struct {
+	name    string
+	content string
+}
+
+// buildXLSX 按给定顺序把各部件写入 zip，只包含读取时用到的共享字符串和工作表
+func buildXLSX(t *testing.T, parts ...xlsxPart) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, part := range parts {
+		w, err := writer.Create(part.name)
+		if err != nil {
+			t.Fatalf("create %s: %v", part.name, err)
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			t.Fatalf("write %s: %v", part.name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func sharedStrings(items ...string) xlsxPart {
+	return xlsxPart{"xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + strings.Join(items, "") + `</sst>`}
+}
+
+func sheet(number int, rows ...string) xlsxPart {
+	return xlsxPart{
+		fmt.Sprintf("xl/worksheets/sheet%d.xml", number),
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + strings.Join(rows, "") + `</sheetData></worksheet>`,
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t,
+		sheet(10, `<row r="1"><c r="A1" t="inlineStr"><is><t>sheet10</t></is></c></row>`),
+		sharedStrings(
+			`<si><t>交易时间</t></si>`,
+			`<si><r><rPr><b/></rPr><t>金</t></r><r><t>额</t></r></si>`,
+			`<si><t>备注</t><rPh sb="0" eb="2"><t>ビコウ</t></rPh></si>`,
+			`<si><t xml:space="preserve"> 午饭 </t></si>`,
+		),
+		sheet(2, `<row r="1"><c r="A1" t="inlineStr"><is><t>sheet2</t></is></c></row>`),
+		sheet(1,
+			`<row r="2"><c r="A2" t="s"><v>0</v></c><c r="B2" t="s"><v>1</v></c><c r="D2" t="s"><v>2</v></c></row>`,
+			`<row r="3"><c r="A3"><v>45352.5</v></c><c r="B3"><v>12.5</v></c><c r="D3" t="s"><v>3</v></c></row>`,
+			`<row r="4"><c r="A4" t="s"><v>99</v></c></row>`,
+			`<row r="5"><c r="A5" t="inlineStr"><is><r><t>加</t></r><r><t>班</t></r></is></c><c><v>8</v></c><c r="AA5"><v>1</v></c></row>`,
+			`<row r="6"><c r="B6"><v></v></c></row>`,
+			`<row r="7"><c r="C7" t="inlineStr"><is><t>末行</t></is></c></row>`,
+		),
+	)
+
+	table, err := importer.ReadTable(data, "", 0)
+	if err != nil {
+		t.Fatalf("read xlsx: %v", err)
+	}
+	if table.Encoding != importer.EncodingUTF8 || table.Delimiter != 0 {
+		t.Errorf("encoding = %q, delimiter = %q", table.Encoding, table.Delimiter)
+	}
+
+	wide := make([]string, 27)
+	wide[0], wide[1], wide[26] = "加班", "8", "1"
+	wantRows := [][]string{
+		{"交易时间", "金额", "", "备注"},
+		{"45352.5", "12.5", "", "午饭"},
+		{"99"},
+		wide,
+		{"", "", "末行"},
+	}
+	if !reflect.DeepEqual(table.Rows, wantRows) {
+		t.Errorf("rows = %q, want %q", table.Rows, wantRows)
+	}
+	if wantLines := []int{2, 3, 4, 5, 7}; !reflect.DeepEqual(table.Lines, wantLines) {
+		t.Errorf("lines = %v, want %v", table.Lines, wantLines)
+	}
+}
+
+func TestReadXLSXLimits(t *testing.T) {
+	repeat := func(count int, item string) []string {
+		items := make([]string, count)
+		for i := range items {
+			items[i] = item
+		}
+		return items
+	}
+	wideRows := make([]string, 2000000/16384+1)
+	for i := range wideRows {
+		wideRows[i] = fmt.Sprintf(`<row r="%d"><c r="XFD%d"><v>1</v></c></row>`, i+1, i+1)
+	}
+	manyRows := make([]string, 100001)
+	for i := range manyRows {
+		manyRows[i] = fmt.Sprintf(`<row r="%d"><c r="A%d"><v>1</v></c></row>`, i+1, i+1)
+	}
+	row := sheet(1, `<row r="1"><c r="A1" t="s"><v>0</v></c></row>`)
+
+	tests := []struct {
+		name  string
+		parts []xlsxPart
+		want  string
+	}{
+		{"no worksheet", []xlsxPart{sharedStrings(`<si><t>a</t></si>`)}, "no worksheet"},
+		{"empty worksheet", []xlsxPart{sheet(1, `<row r="1"><c r="A1"><v> </v></c></row>`)}, "no rows"},
+		{"bad cell reference", []xlsxPart{sheet(1, `<row r="1"><c r="1A"><v>1</v></c></row>`)}, "cell reference"},
+		{"column beyond XFD", []xlsxPart{sheet(1, `<row r="1"><c r="XFE1"><v>1</v></c></row>`)}, "cell reference"},
+		{"too many rows", []xlsxPart{sheet(1, manyRows...)}, "more than 100000 rows"},
+		{"too many cells", []xlsxPart{sheet(1, wideRows...)}, "more than 2000000 cells"},
+		{"too many shared strings", []xlsxPart{sharedStrings(repeat(2000001, `<si/>`)...), row}, "more than 2000000 shared strings"},
+		{"shared strings too long", []xlsxPart{sharedStrings(repeat(33, `<si><t>`+strings.Repeat("a", 1<<20)+`</t></si>`)...), row}, "shared strings exceed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importer.ReadTable(buildXLSX(t, tt.parts...), "", 0)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
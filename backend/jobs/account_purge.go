@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"finmind-backend/database"
+	"gorm.io/gorm"
+)
+
+func StartAccountPurge(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := database.PurgeScheduledUsers(db, time.Now())
+			if err != nil {
+				log.Printf("[AccountPurge] Failed to purge scheduled accounts: %v", err)
+			} else if purged > 0 {
+				log.Printf("[AccountPurge] Purged %d accounts", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
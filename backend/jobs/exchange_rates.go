@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"finmind-backend/exchange"
+	"gorm.io/gorm"
+)
+
+func StartExchangeRateSync(db *gorm.DB, provider exchange.Provider, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			imported, err := exchange.Sync(ctx, db, provider)
+			cancel()
+			if err != nil {
+				log.Printf("[ExchangeRateSync] Failed to sync rates from %s: %v", provider.Name(), err)
+			} else {
+				log.Printf("[ExchangeRateSync] Imported %d rates from %s", imported, provider.Name())
+			}
+			<-ticker.C
+		}
+	}()
+}
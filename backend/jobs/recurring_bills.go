@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"finmind-backend/handlers"
+	"finmind-backend/models"
+	"finmind-backend/recurring"
+	"gorm.io/gorm"
+)
+
+// StartRecurringBills 定期为到期的周期规则生成账单。启动时立即执行一轮，补齐停机期间错过的账单
+func StartRecurringBills(db *gorm.DB, alerts *handlers.BudgetAlerter, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			bills, err := recurring.Materialize(db, time.Now().UTC())
+			if err != nil {
+				log.Printf("[RecurringBills] Failed to materialize recurring bills: %v", err)
+			} else if len(bills) > 0 {
+				log.Printf("[RecurringBills] Created %d bills", len(bills))
+
+				byUser := make(map[uint][]models.Bill)
+				for _, bill := range bills {
+					byUser[bill.UserID] = append(byUser[bill.UserID], bill)
+				}
+				for userID, userBills := range byUser {
+					alerts.CheckBills(userID, userBills...)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,295 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"finmind-backend/config"
+	"finmind-backend/models"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// 遇到未知 kid 时按需重新加载密钥的最短间隔，防止伪造的 kid 放大数据库查询
+const keyReloadLimit = time.Minute
+
+type signingKey struct {
+	kid       string
+	algorithm string
+	private   crypto.Signer
+	public    crypto.PublicKey
+	createdAt time.Time
+}
+
+type Manager struct {
+	db               *gorm.DB
+	algorithm        string
+	secret           []byte
+	rotationInterval time.Duration
+	retention        time.Duration
+
+	mu         sync.RWMutex
+	active     *signingKey
+	keys       map[string]*signingKey
+	reloadedAt time.Time
+
+	// 串行化按需重新加载，避免并发请求同时查询数据库
+	reloadMu sync.Mutex
+}
+
+func NewManager(db *gorm.DB, cfg *config.Config) (*Manager, error) {
+	m := &Manager{
+		db:               db,
+		algorithm:        cfg.JWTAlgorithm,
+		secret:           []byte(cfg.JWTSecret),
+		rotationInterval: cfg.JWTKeyRotationInterval,
+		retention:        cfg.JWTKeyRetention,
+	}
+
+	switch m.algorithm {
+	case AlgorithmHS256:
+		return m, nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", m.algorithm)
+	}
+
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	if err := m.rotateIfDue(time.Now()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Start 定期从数据库重新加载密钥，以便多个节点共享轮换结果，并在到期时轮换签名密钥
+func (m *Manager) Start(interval time.Duration) {
+	if m.algorithm == AlgorithmHS256 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := m.reload(); err != nil {
+				log.Printf("[JWTKeys] Failed to reload signing keys: %v", err)
+				continue
+			}
+			if err := m.rotateIfDue(time.Now()); err != nil {
+				log.Printf("[JWTKeys] Failed to rotate signing key: %v", err)
+			}
+		}
+	}()
+}
+
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	if m.algorithm == AlgorithmHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	}
+
+	m.mu.RLock()
+	active := m.active
+	m.mu.RUnlock()
+	if active == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(active.algorithm), claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if m.algorithm == AlgorithmHS256 {
+		if token.Method.Alg() != AlgorithmHS256 {
+			return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+		}
+		return m.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := m.lookup(kid)
+	if !ok {
+		key, ok = m.reloadForUnknownKid(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+func (m *Manager) lookup(kid string) (*signingKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[kid]
+	return key, ok
+}
+
+// reloadForUnknownKid 在其他节点刚轮换密钥、本节点尚未定期重新加载时立即从数据库加载新密钥，
+// 每分钟最多一次
+func (m *Manager) reloadForUnknownKid(kid string) (*signingKey, bool) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	// 等待期间其他请求可能已经加载过
+	if key, ok := m.lookup(kid); ok {
+		return key, true
+	}
+
+	m.mu.RLock()
+	reloadedAt := m.reloadedAt
+	m.mu.RUnlock()
+	if time.Since(reloadedAt) < keyReloadLimit {
+		return nil, false
+	}
+
+	if err := m.reload(); err != nil {
+		log.Printf("[JWTKeys] Failed to reload signing keys for kid %s: %v", kid, err)
+		return nil, false
+	}
+	return m.lookup(kid)
+}
+
+func (m *Manager) JWKS() JSONWebKeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(m.keys))}
+	for _, key := range m.keys {
+		if jwk, ok := toJWK(key.kid, key.algorithm, key.public); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func (m *Manager) Rotate() error {
+	private, err := generateKey(m.algorithm)
+	if err != nil {
+		return err
+	}
+	encrypted, err := encryptPrivateKey(m.secret, private)
+	if err != nil {
+		return err
+	}
+	public, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return err
+	}
+	kid, err := newKid()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(m.retention)
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		// 旧密钥不再用于签名，但在保留期内仍可用于校验已签发的令牌
+		if err := tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL").
+			Updates(map[string]interface{}{"retired_at": now, "expires_at": expiresAt}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.SigningKey{
+			Kid:        kid,
+			Algorithm:  m.algorithm,
+			PrivateKey: encrypted,
+			PublicKey:  public,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[JWTKeys] Rotated signing key, new kid %s", kid)
+	return m.reload()
+}
+
+func (m *Manager) rotateIfDue(now time.Time) error {
+	m.mu.RLock()
+	active := m.active
+	m.mu.RUnlock()
+
+	if active != nil && now.Sub(active.createdAt) < m.rotationInterval {
+		return nil
+	}
+	return m.Rotate()
+}
+
+func (m *Manager) reload() error {
+	now := time.Now()
+	if err := m.db.Where("expires_at IS NOT NULL AND expires_at < ?", now).Delete(&models.SigningKey{}).Error; err != nil {
+		return err
+	}
+
+	var records []models.SigningKey
+	if err := m.db.Order("created_at ASC").Find(&records).Error; err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(records))
+	var active *signingKey
+	for _, record := range records {
+		publicKey, err := x509.ParsePKIXPublicKey(record.PublicKey)
+		if err != nil {
+			log.Printf("[JWTKeys] Skipping key %s: %v", record.Kid, err)
+			continue
+		}
+		key := &signingKey{
+			kid:       record.Kid,
+			algorithm: record.Algorithm,
+			public:    publicKey,
+			createdAt: record.CreatedAt,
+		}
+		keys[key.kid] = key
+
+		if record.RetiredAt != nil || record.Algorithm != m.algorithm {
+			continue
+		}
+		// 私钥无法解密（例如 JWT_SECRET 已更换）时不能继续签名，等待轮换出新密钥
+		private, err := decryptPrivateKey(m.secret, record.PrivateKey)
+		if err != nil {
+			log.Printf("[JWTKeys] Cannot decrypt key %s: %v", record.Kid, err)
+			continue
+		}
+		key.private = private
+		active = key
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.active = active
+	m.reloadedAt = now
+	m.mu.Unlock()
+	return nil
+}
+
+func newKid() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -3,11 +3,18 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"finmind-backend/config"
 	"finmind-backend/database"
+	"finmind-backend/exchange"
+	"finmind-backend/handlers"
+	"finmind-backend/jobs"
+	"finmind-backend/jwtkeys"
+	"finmind-backend/mailer"
+	"finmind-backend/notify"
 	"finmind-backend/routes"
 )
 
@@ -17,6 +24,9 @@ func main() {
 	}
 
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
 
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -31,9 +41,26 @@ func main() {
 		log.Fatal("Failed to seed database:", err)
 	}
 
+	keyManager, err := jwtkeys.NewManager(db, cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT signing keys:", err)
+	}
+	keyManager.Start(time.Hour)
+
+	// HTTP 接口和周期账单任务共用同一个预算提醒器，通知渠道配置只构建一次
+	mail := mailer.New(cfg)
+	budgetAlerter := handlers.NewBudgetAlerter(db, notify.New(cfg, db, mail))
+
+	jobs.StartAccountPurge(db, time.Hour)
+	jobs.StartRecurringBills(db, budgetAlerter, cfg.RecurringBillInterval)
+
+	if cfg.ExchangeRateCSV != "" {
+		jobs.StartExchangeRateSync(db, exchange.NewCSVProvider(cfg.ExchangeRateCSV), cfg.ExchangeRateSyncInterval)
+	}
+
 	r := gin.Default()
 
-	routes.SetupRoutes(r, db, cfg)
+	routes.SetupRoutes(r, db, cfg, keyManager, mail, budgetAlerter)
 
 	port := os.Getenv("PORT")
 	if port == "" {
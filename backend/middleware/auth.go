@@ -1,23 +1,31 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"finmind-backend/config"
+	"gorm.io/gorm"
+	"finmind-backend/jwtkeys"
+	"finmind-backend/models"
 )
 
+const sessionTouchInterval = time.Minute
+
 type Claims struct {
 	UserID    uint   `json:"user_id"`
 	Email     string `json:"email"`
 	TokenType string `json:"token_type"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func AuthMiddleware(keys *jwtkeys.Manager, db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -33,10 +41,13 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(tokenString, models.PersonalAccessTokenPrefix) {
+			authenticatePersonalAccessToken(c, db, tokenString)
+			return
+		}
+
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -50,12 +61,59 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		var session models.Session
+		if err := db.Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID).First(&session).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found"})
+			c.Abort()
+			return
+		}
+
+		now := time.Now()
+		if !session.IsActive(now) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		// 降低写入频率，避免每个请求都更新会话
+		if now.Sub(session.LastUsedAt) > sessionTouchInterval {
+			db.Model(&session).UpdateColumn("last_used_at", now)
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("session_id", session.ID)
 		c.Next()
 	}
 }
 
+func authenticatePersonalAccessToken(c *gin.Context, db *gorm.DB, tokenString string) {
+	sum := sha256.Sum256([]byte(tokenString))
+
+	var token models.PersonalAccessToken
+	if err := db.Preload("User").Where("token_hash = ?", hex.EncodeToString(sum[:])).First(&token).Error; err != nil || token.User.ID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	now := time.Now()
+	if !token.IsActive(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired or revoked"})
+		c.Abort()
+		return
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > sessionTouchInterval {
+		db.Model(&token).UpdateColumn("last_used_at", now)
+	}
+
+	c.Set("user_id", token.UserID)
+	c.Set("user_email", token.User.Email)
+	c.Set("token_scopes", token.ScopeList())
+	c.Next()
+}
+
 func GetUserID(c *gin.Context) (uint, error) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -64,6 +122,14 @@ func GetUserID(c *gin.Context) (uint, error) {
 	return userID.(uint), nil
 }
 
+func GetSessionID(c *gin.Context) (uint, error) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0, jwt.ErrTokenInvalidClaims
+	}
+	return sessionID.(uint), nil
+}
+
 func GetUserIDFromParam(c *gin.Context, paramName string) (uint, error) {
 	idStr := c.Param(paramName)
 	id, err := strconv.ParseUint(idStr, 10, 32)
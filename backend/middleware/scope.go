@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope 仅约束个人访问令牌：只读请求需要 read 权限，其余请求需要 write 权限，
+// 登录会话签发的令牌拥有全部权限
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, ok := GetTokenScopes(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		required := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = read
+		}
+
+		if required == "" || !hasScope(scopes, required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient token scope", "required_scope": required})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession 拒绝个人访问令牌，用于账号管理等只允许登录会话访问的接口
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetTokenScopes(c); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot access this endpoint"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AllowsScope 判断当前请求能否访问 scope 对应的数据：登录会话不受权限范围限制
+func AllowsScope(c *gin.Context, scope string) bool {
+	scopes, ok := GetTokenScopes(c)
+	return !ok || hasScope(scopes, scope)
+}
+
+func GetTokenScopes(c *gin.Context) ([]string, bool) {
+	scopes, exists := c.Get("token_scopes")
+	if !exists {
+		return nil, false
+	}
+	return scopes.([]string), true
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"finmind-backend/config"
+	"finmind-backend/models"
+)
+
+func EmailVerificationMiddleware(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.EmailVerificationPolicy == config.EmailVerificationOff {
+			c.Next()
+			return
+		}
+
+		if cfg.EmailVerificationPolicy == config.EmailVerificationReadOnly && isReadOnlyMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("id", "email_verified_at", "created_at").First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		// 宽限期内未验证的账号不受限制
+		if user.EmailVerifiedAt != nil || time.Since(user.CreatedAt) < cfg.EmailVerificationGracePeriod {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email verification required"})
+		c.Abort()
+	}
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
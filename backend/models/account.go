@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+	"finmind-backend/money"
+)
+
+const (
+	AccountTypeCash       = "cash"
+	AccountTypeBankCard   = "bank_card"
+	AccountTypeCreditCard = "credit_card"
+	AccountTypeEWallet    = "e_wallet"
+)
+
+// Account 的期初余额和按账单计算的余额都以账户币种 Currency 计，关联账户的账单必须使用相同币种
+type Account struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	UserID         uint           `json:"user_id" gorm:"not null;index;uniqueIndex:idx_accounts_user_name,where:deleted_at IS NULL"`
+	Name           string         `json:"name" gorm:"not null;uniqueIndex:idx_accounts_user_name"`
+	Type           string         `json:"type" gorm:"not null;check:type IN ('cash','bank_card','credit_card','e_wallet')"`
+	Institution    string         `json:"institution"`
+	Currency       string         `json:"currency" gorm:"size:3;not null;default:'CNY'"`
+	OpeningBalance money.Amount   `json:"opening_balance" gorm:"column:opening_balance_minor;not null;default:0"`
+	Icon           string         `json:"icon"`
+	Color          string         `json:"color"`
+	Archived       bool           `json:"archived" gorm:"not null;default:false"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	User  User   `json:"-" gorm:"foreignKey:UserID"`
+	Bills []Bill `json:"-" gorm:"foreignKey:AccountID"`
+}
+
+type AccountResponse struct {
+	ID             uint         `json:"id"`
+	Name           string       `json:"name"`
+	Type           string       `json:"type"`
+	Institution    string       `json:"institution"`
+	Currency       string       `json:"currency"`
+	OpeningBalance money.Amount `json:"opening_balance"`
+	Balance        money.Amount `json:"balance"`
+	Icon           string       `json:"icon"`
+	Color          string       `json:"color"`
+	Archived       bool         `json:"archived"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+func (a *Account) ToResponse(balance money.Amount) AccountResponse {
+	return AccountResponse{
+		ID:             a.ID,
+		Name:           a.Name,
+		Type:           a.Type,
+		Institution:    a.Institution,
+		Currency:       a.Currency,
+		OpeningBalance: a.OpeningBalance,
+		Balance:        balance,
+		Icon:           a.Icon,
+		Color:          a.Color,
+		Archived:       a.Archived,
+		CreatedAt:      a.CreatedAt,
+		UpdatedAt:      a.UpdatedAt,
+	}
+}
+
+type CreateAccountRequest struct {
+	Name           string       `json:"name" binding:"required,max=100"`
+	Type           string       `json:"type" binding:"required,oneof=cash bank_card credit_card e_wallet"`
+	Institution    string       `json:"institution" binding:"max=100"`
+	Currency       string       `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	OpeningBalance money.Amount `json:"opening_balance"`
+	Icon           string       `json:"icon"`
+	Color          string       `json:"color"`
+}
+
+type UpdateAccountRequest struct {
+	Name           string        `json:"name" binding:"omitempty,max=100"`
+	Type           string        `json:"type" binding:"omitempty,oneof=cash bank_card credit_card e_wallet"`
+	Institution    *string       `json:"institution" binding:"omitempty,max=100"`
+	Currency       string        `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	OpeningBalance *money.Amount `json:"opening_balance"`
+	Icon           string        `json:"icon"`
+	Color          string        `json:"color"`
+	Archived       *bool         `json:"archived"`
+}
+
+type AccountBalancesQuery struct {
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+}
+
+type AccountDailyBalance struct {
+	Date        string       `json:"date"`
+	Income      money.Amount `json:"income"`
+	Expense     money.Amount `json:"expense"`
+	TransferIn  money.Amount `json:"transfer_in"`
+	TransferOut money.Amount `json:"transfer_out"`
+	Balance     money.Amount `json:"balance"`
+}
+
+type AccountBalancesResponse struct {
+	AccountID      uint                  `json:"account_id"`
+	Currency       string                `json:"currency"`
+	StartDate      string                `json:"start_date"`
+	EndDate        string                `json:"end_date"`
+	OpeningBalance money.Amount          `json:"opening_balance"`
+	ClosingBalance money.Amount          `json:"closing_balance"`
+	Days           []AccountDailyBalance `json:"days"`
+}
@@ -1,81 +1,171 @@
 package models
 
 import (
+	"fmt"
 	"time"
 	"gorm.io/gorm"
+	"finmind-backend/money"
+)
+
+const (
+	BillTypeIncome   = "income"
+	BillTypeExpense  = "expense"
+	BillTypeTransfer = "transfer"
 )
 
 type Bill struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	UserID      uint           `json:"user_id" gorm:"not null;index"`
-	CategoryID  uint           `json:"category_id" gorm:"not null;index"`
-	Type        string         `json:"type" gorm:"not null;check:type IN ('income','expense')"`
-	Amount      float64        `json:"amount" gorm:"not null;check:amount > 0"`
-	Merchant    string         `json:"merchant" gorm:"not null"`
-	Description string         `json:"description"`
-	BillTime    time.Time      `json:"bill_time" gorm:"not null;index"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	UserID          uint           `json:"user_id" gorm:"not null;index;uniqueIndex:idx_bills_user_client"`
+	ClientID        *string        `json:"client_id,omitempty" gorm:"uniqueIndex:idx_bills_user_client"`
+	CategoryID      *uint          `json:"category_id,omitempty" gorm:"index"`
+	AccountID       *uint          `json:"account_id,omitempty" gorm:"index"`
+	ToAccountID     *uint          `json:"to_account_id,omitempty" gorm:"index"`
+	GoalID          *uint          `json:"goal_id,omitempty" gorm:"index"`
+	RecurringRuleID *uint          `json:"recurring_rule_id,omitempty" gorm:"index"`
+	Channel         string         `json:"channel"`
+	Type            string         `json:"type" gorm:"not null;check:type IN ('income','expense','transfer')"`
+	Amount          money.Amount   `json:"amount" gorm:"column:amount_minor;not null;check:amount_minor > 0"`
+	Currency        string         `json:"currency" gorm:"size:3;not null;default:'CNY'"`
+	Merchant        string         `json:"merchant" gorm:"not null"`
+	Description     string         `json:"description"`
+	BillTime        time.Time      `json:"bill_time" gorm:"not null;index"`
+	Version         uint           `json:"version" gorm:"not null;default:1"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 
-	User     User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Category Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	User      User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Category  Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Account   *Account `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	ToAccount *Account `json:"to_account,omitempty" gorm:"foreignKey:ToAccountID"`
+	Goal      *Goal    `json:"goal,omitempty" gorm:"foreignKey:GoalID"`
 }
 
 type BillResponse struct {
-	ID          uint      `json:"id"`
-	Type        string    `json:"type"`
-	Amount      float64   `json:"amount"`
-	Category    string    `json:"category"`
-	Merchant    string    `json:"merchant"`
-	Description string    `json:"description"`
-	Time        time.Time `json:"time"`
-	Synced      bool      `json:"synced"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              uint         `json:"id"`
+	ClientID        string       `json:"client_id,omitempty"`
+	Type            string       `json:"type"`
+	Amount          money.Amount `json:"amount"`
+	Currency        string       `json:"currency"`
+	Category        string       `json:"category"`
+	AccountID       *uint        `json:"account_id,omitempty"`
+	ToAccountID     *uint        `json:"to_account_id,omitempty"`
+	GoalID          *uint        `json:"goal_id,omitempty"`
+	RecurringRuleID *uint        `json:"recurring_rule_id,omitempty"`
+	Channel         string       `json:"channel"`
+	Merchant        string       `json:"merchant"`
+	Description     string       `json:"description"`
+	Time            time.Time    `json:"time"`
+	Synced          bool         `json:"synced"`
+	Version         uint         `json:"version"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+func (b *Bill) ETag() string {
+	return fmt.Sprintf("\"%d-%d\"", b.ID, b.Version)
 }
 
 func (b *Bill) ToResponse() BillResponse {
+	clientID := ""
+	if b.ClientID != nil {
+		clientID = *b.ClientID
+	}
+
 	return BillResponse{
-		ID:          b.ID,
-		Type:        b.Type,
-		Amount:      b.Amount,
-		Category:    b.Category.Name,
-		Merchant:    b.Merchant,
-		Description: b.Description,
-		Time:        b.BillTime,
-		Synced:      true,
-		CreatedAt:   b.CreatedAt,
-		UpdatedAt:   b.UpdatedAt,
+		ID:              b.ID,
+		ClientID:        clientID,
+		Type:            b.Type,
+		Amount:          b.Amount,
+		Currency:        b.Currency,
+		Category:        b.Category.Name,
+		AccountID:       b.AccountID,
+		ToAccountID:     b.ToAccountID,
+		GoalID:          b.GoalID,
+		RecurringRuleID: b.RecurringRuleID,
+		Channel:         b.Channel,
+		Merchant:        b.Merchant,
+		Description:     b.Description,
+		Time:            b.BillTime,
+		Synced:          true,
+		Version:         b.Version,
+		CreatedAt:       b.CreatedAt,
+		UpdatedAt:       b.UpdatedAt,
 	}
 }
 
 type CreateBillRequest struct {
-	Type        string    `json:"type" binding:"required,oneof=income expense"`
-	Amount      float64   `json:"amount" binding:"required,gt=0"`
-	CategoryID  uint      `json:"category_id" binding:"required"`
-	Merchant    string    `json:"merchant" binding:"required"`
-	Description string    `json:"description"`
-	BillTime    time.Time `json:"bill_time" binding:"required"`
+	Type        string       `json:"type" binding:"required,oneof=income expense transfer"`
+	Amount      money.Amount `json:"amount" binding:"required,gt=0"`
+	Currency    string       `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	CategoryID  uint         `json:"category_id" binding:"required_unless=Type transfer"`
+	AccountID   *uint        `json:"account_id"`
+	ToAccountID *uint        `json:"to_account_id"`
+	GoalID      *uint        `json:"goal_id"`
+	Channel     string       `json:"channel" binding:"max=50"`
+	Merchant    string       `json:"merchant" binding:"required_unless=Type transfer"`
+	Description string       `json:"description"`
+	BillTime    time.Time    `json:"bill_time" binding:"required"`
 }
 
 type UpdateBillRequest struct {
-	Type        string    `json:"type" binding:"omitempty,oneof=income expense"`
-	Amount      float64   `json:"amount" binding:"omitempty,gt=0"`
-	CategoryID  uint      `json:"category_id" binding:"omitempty"`
-	Merchant    string    `json:"merchant" binding:"omitempty"`
-	Description string    `json:"description"`
-	BillTime    time.Time `json:"bill_time" binding:"omitempty"`
+	Type        string       `json:"type" binding:"omitempty,oneof=income expense transfer"`
+	Amount      money.Amount `json:"amount" binding:"omitempty,gt=0"`
+	Currency    string       `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	CategoryID  uint         `json:"category_id" binding:"omitempty"`
+	AccountID   *uint        `json:"account_id"`
+	ToAccountID *uint        `json:"to_account_id"`
+	GoalID      *uint        `json:"goal_id"`
+	Channel     string       `json:"channel" binding:"max=50"`
+	Merchant    string       `json:"merchant" binding:"omitempty"`
+	Description string       `json:"description"`
+	BillTime    time.Time    `json:"bill_time" binding:"omitempty"`
+}
+
+type SyncBillItem struct {
+	ClientID    string       `json:"client_id" binding:"required,max=64"`
+	Version     uint         `json:"version"`
+	Type        string       `json:"type" binding:"required,oneof=income expense transfer"`
+	Amount      money.Amount `json:"amount" binding:"required,gt=0"`
+	Currency    string       `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	CategoryID  uint         `json:"category_id"`
+	Category    string       `json:"category"`
+	AccountID   *uint        `json:"account_id"`
+	ToAccountID *uint        `json:"to_account_id"`
+	GoalID      *uint        `json:"goal_id"`
+	Channel     string       `json:"channel" binding:"max=50"`
+	Merchant    string       `json:"merchant" binding:"required_unless=Type transfer"`
+	Description string       `json:"description"`
+	BillTime    time.Time    `json:"bill_time"`
+}
+
+type SyncBillsRequest struct {
+	Bills []SyncBillItem `json:"bills" binding:"required,min=1,max=500,dive"`
+}
+
+type SyncBillResult struct {
+	ClientID string        `json:"client_id"`
+	ID       uint          `json:"id,omitempty"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Bill     *BillResponse `json:"bill,omitempty"`
+}
+
+type SyncBillsResponse struct {
+	SyncedCount int              `json:"synced_count"`
+	FailedCount int              `json:"failed_count"`
+	Results     []SyncBillResult `json:"results"`
 }
 
 type BillsQuery struct {
 	Page       int    `form:"page,default=1" binding:"min=1"`
 	Limit      int    `form:"limit,default=20" binding:"min=1,max=100"`
-	Type       string `form:"type" binding:"omitempty,oneof=income expense"`
+	Type       string `form:"type" binding:"omitempty,oneof=income expense transfer"`
 	CategoryID uint   `form:"category_id"`
+	AccountID  uint   `form:"account_id"`
 	StartDate  string `form:"start_date"`
 	EndDate    string `form:"end_date"`
 	Search     string `form:"search"`
 	SortBy     string `form:"sort_by,default=bill_time" binding:"omitempty,oneof=bill_time amount created_at"`
 	SortOrder  string `form:"sort_order,default=desc" binding:"omitempty,oneof=asc desc"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+	"finmind-backend/money"
+)
+
+// BillImportRequest 以 multipart 表单提交：首次上传 file，预览后可凭 import_id 提交同一文件而无需重新上传。
+// format 为 auto 时先识别支付宝、微信支付账单，都不匹配时按通用 CSV 解析；
+// mapping 为 JSON 格式的列映射，通用 CSV 未提交时按表头自动识别，专用格式只使用其中的分类映射
+type BillImportRequest struct {
+	Format      string `form:"format" binding:"omitempty,oneof=auto csv alipay wechat"`
+	ImportID    string `form:"import_id" binding:"omitempty,hexadecimal,len=32"`
+	Mapping     string `form:"mapping"`
+	Encoding    string `form:"encoding" binding:"omitempty,oneof=utf-8 gbk"`
+	Delimiter   string `form:"delimiter" binding:"omitempty,len=1"`
+	Timezone    string `form:"timezone"`
+	Currency    string `form:"currency" binding:"omitempty,iso4217,supported_currency"`
+	AccountID   *uint  `form:"account_id"`
+	DryRun      *bool  `form:"dry_run"`
+	SkipInvalid bool   `form:"skip_invalid"`
+}
+
+// BillImportMapping 以表头名称指定各字段所在的列，HeaderRow 为表头在文件中的行号（从 1 开始，0 表示第一个非空行）。
+// 分类先按 CategoryMap 映射，再按名称匹配同类型分类，都未匹配时使用对应类型的默认分类
+type BillImportMapping struct {
+	HeaderRow                int             `json:"header_row"`
+	Time                     string          `json:"time"`
+	TimeFormat               string          `json:"time_format,omitempty"`
+	Amount                   string          `json:"amount"`
+	Type                     string          `json:"type,omitempty"`
+	Category                 string          `json:"category,omitempty"`
+	Merchant                 string          `json:"merchant,omitempty"`
+	Channel                  string          `json:"channel,omitempty"`
+	Description              string          `json:"description,omitempty"`
+	Currency                 string          `json:"currency,omitempty"`
+	CategoryMap              map[string]uint `json:"category_map,omitempty"`
+	DefaultIncomeCategoryID  uint            `json:"default_income_category_id,omitempty"`
+	DefaultExpenseCategoryID uint            `json:"default_expense_category_id,omitempty"`
+}
+
+// ImportedBill 是从文件中解析出的一行账单，Row 为文件中的行号（从 1 开始）
+type ImportedBill struct {
+	Row         int          `json:"row"`
+	Type        string       `json:"type"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Category    string       `json:"category"`
+	CategoryID  *uint        `json:"category_id,omitempty"`
+	Merchant    string       `json:"merchant"`
+	Channel     string       `json:"channel"`
+	Description string       `json:"description"`
+	Reference   string       `json:"reference,omitempty"`
+	Time        time.Time    `json:"time"`
+}
+
+const BillImportPreviewLimit = 200
+
+type BillImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// BillImportPreview 的 Bills 最多列出前 BillImportPreviewLimit 条有效账单，Errors 列出全部错误行，
+// Ignored 列出按格式规则跳过的行（如已关闭、已退款的交易）
+type BillImportPreview struct {
+	ImportID  string             `json:"import_id"`
+	Format    string             `json:"format"`
+	Encoding  string             `json:"encoding"`
+	Delimiter string             `json:"delimiter,omitempty"`
+	Columns   []string           `json:"columns"`
+	Mapping   *BillImportMapping `json:"mapping,omitempty"`
+	TotalRows int                `json:"total_rows"`
+	ValidRows int                `json:"valid_rows"`
+	Bills     []ImportedBill     `json:"bills"`
+	Errors    []BillImportError  `json:"errors"`
+	Ignored   []BillImportError  `json:"ignored"`
+}
+
+type BillImportResult struct {
+	Imported   int               `json:"imported"`
+	Duplicates int               `json:"duplicates"`
+	Skipped    int               `json:"skipped"`
+	Ignored    int               `json:"ignored"`
+	Errors     []BillImportError `json:"errors"`
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"gorm.io/gorm"
+	"finmind-backend/money"
+)
+
+const (
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodYearly  = "yearly"
+)
+
+const DefaultBudgetThresholds = "50,80,100"
+
+// Budget 是某个周期内的支出上限，以用户本位币计。CategoryID 为空表示不区分分类的总预算
+type Budget struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	Name       string         `json:"name" gorm:"not null"`
+	CategoryID *uint          `json:"category_id,omitempty" gorm:"index"`
+	Amount     money.Amount   `json:"amount" gorm:"column:amount_minor;not null;check:amount_minor > 0"`
+	Period     string         `json:"period" gorm:"not null;default:'monthly';check:period IN ('weekly','monthly','yearly')"`
+	Rollover   bool           `json:"rollover" gorm:"not null;default:false"`
+	Thresholds string         `json:"-" gorm:"not null;default:'50,80,100'"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	User     User      `json:"-" gorm:"foreignKey:UserID"`
+	Category *Category `json:"-" gorm:"foreignKey:CategoryID"`
+}
+
+type BudgetResponse struct {
+	ID           uint         `json:"id"`
+	Name         string       `json:"name"`
+	CategoryID   *uint        `json:"category_id,omitempty"`
+	CategoryName string       `json:"category_name,omitempty"`
+	Amount       money.Amount `json:"amount"`
+	Period       string       `json:"period"`
+	Rollover     bool         `json:"rollover"`
+	Thresholds   []int        `json:"thresholds"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+func (b *Budget) ToResponse() BudgetResponse {
+	response := BudgetResponse{
+		ID:         b.ID,
+		Name:       b.Name,
+		CategoryID: b.CategoryID,
+		Amount:     b.Amount,
+		Period:     b.Period,
+		Rollover:   b.Rollover,
+		Thresholds: b.ThresholdList(),
+		CreatedAt:  b.CreatedAt,
+		UpdatedAt:  b.UpdatedAt,
+	}
+	if b.Category != nil {
+		response.CategoryName = b.Category.Name
+	}
+	return response
+}
+
+// ThresholdList 返回升序排列的提醒阈值（已用额度的百分比）
+func (b *Budget) ThresholdList() []int {
+	thresholds := []int{}
+	for _, field := range strings.Split(b.Thresholds, ",") {
+		if threshold, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+func (b *Budget) SetThresholds(thresholds []int) {
+	seen := make(map[int]bool, len(thresholds))
+	fields := make([]string, 0, len(thresholds))
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	for _, threshold := range sorted {
+		if !seen[threshold] {
+			seen[threshold] = true
+			fields = append(fields, strconv.Itoa(threshold))
+		}
+	}
+	b.Thresholds = strings.Join(fields, ",")
+}
+
+// PeriodRange 返回 at 所在预算周期的起止时间（UTC，结束时间为周期最后一秒）。周预算从周一开始
+func (b *Budget) PeriodRange(at time.Time) (time.Time, time.Time) {
+	at = at.UTC()
+	var start, next time.Time
+	switch b.Period {
+	case BudgetPeriodWeekly:
+		offset := (int(at.Weekday()) + 6) % 7
+		start = time.Date(at.Year(), at.Month(), at.Day()-offset, 0, 0, 0, 0, time.UTC)
+		next = start.AddDate(0, 0, 7)
+	case BudgetPeriodYearly:
+		start = time.Date(at.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		next = start.AddDate(1, 0, 0)
+	default:
+		start = time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+		next = start.AddDate(0, 1, 0)
+	}
+	return start, next.Add(-time.Second)
+}
+
+type CreateBudgetRequest struct {
+	Name       string       `json:"name" binding:"required,max=100"`
+	CategoryID *uint        `json:"category_id"`
+	Amount     money.Amount `json:"amount" binding:"required,gt=0"`
+	Period     string       `json:"period" binding:"omitempty,oneof=weekly monthly yearly"`
+	Rollover   bool         `json:"rollover"`
+	Thresholds []int        `json:"thresholds" binding:"omitempty,max=10,dive,min=1,max=1000"`
+}
+
+type UpdateBudgetRequest struct {
+	Name       string       `json:"name" binding:"omitempty,max=100"`
+	CategoryID *uint        `json:"category_id"`
+	Amount     money.Amount `json:"amount" binding:"omitempty,gt=0"`
+	Period     string       `json:"period" binding:"omitempty,oneof=weekly monthly yearly"`
+	Rollover   *bool        `json:"rollover"`
+	Thresholds *[]int       `json:"thresholds" binding:"omitempty,max=10,dive,min=1,max=1000"`
+}
+
+type BudgetProgressQuery struct {
+	Date string `form:"date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type BudgetProgress struct {
+	Budget      BudgetResponse `json:"budget"`
+	PeriodStart string         `json:"period_start"`
+	PeriodEnd   string         `json:"period_end"`
+	RolledOver  money.Amount   `json:"rolled_over"`
+	Available   money.Amount   `json:"available"`
+	Spent       money.Amount   `json:"spent"`
+	Remaining   money.Amount   `json:"remaining"`
+	Percent     float64        `json:"percent"`
+	Projected   money.Amount   `json:"projected"`
+	DaysElapsed int            `json:"days_elapsed"`
+	DaysTotal   int            `json:"days_total"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const DefaultCurrency = "CNY"
+
+const (
+	ExchangeRateSourceManual = "manual"
+	ExchangeRateDateFormat   = "2006-01-02"
+)
+
+// ExchangeRate 表示某日 1 单位 Base 可兑换的 Quote 数量，UserID 为 0 的汇率由数据源导入、所有用户共享
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;default:0;uniqueIndex:idx_exchange_rates_key"`
+	Base      string    `json:"base" gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_key"`
+	Quote     string    `json:"quote" gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_key"`
+	Date      string    `json:"date" gorm:"size:10;not null;uniqueIndex:idx_exchange_rates_key;index"`
+	Rate      float64   `json:"rate" gorm:"not null;check:rate > 0"`
+	Source    string    `json:"source" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateExchangeRateRequest struct {
+	Base  string  `json:"base" binding:"required,iso4217,supported_currency"`
+	Quote string  `json:"quote" binding:"required,iso4217,supported_currency,nefield=Base"`
+	Date  string  `json:"date" binding:"required,datetime=2006-01-02"`
+	Rate  float64 `json:"rate" binding:"required,gt=0"`
+}
+
+type ExchangeRatesQuery struct {
+	Base  string `form:"base" binding:"omitempty,iso4217,supported_currency"`
+	Quote string `form:"quote" binding:"omitempty,iso4217,supported_currency"`
+	Date  string `form:"date" binding:"omitempty,datetime=2006-01-02"`
+	Limit int    `form:"limit,default=100" binding:"min=1,max=500"`
+}
+
+type ConvertCurrencyQuery struct {
+	Amount string  `form:"amount" binding:"required"`
+	From   string  `form:"from" binding:"required,iso4217,supported_currency"`
+	To     string  `form:"to" binding:"required,iso4217,supported_currency"`
+	Date   string  `form:"date" binding:"omitempty,datetime=2006-01-02"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+	"finmind-backend/money"
+)
+
+const (
+	ForecastSourceRecurringRule = "recurring_rule"
+	ForecastSourceSubscription  = "subscription"
+)
+
+type ForecastQuery struct {
+	Days         int `form:"days,default=30" binding:"min=1,max=366"`
+	LookbackDays int `form:"lookback_days,default=90" binding:"min=7,max=366"`
+}
+
+// ForecastItem 是预测期内某一天的一笔计划收支，Amount 为本位币金额
+type ForecastItem struct {
+	Source          string       `json:"source"`
+	RecurringRuleID *uint        `json:"recurring_rule_id,omitempty"`
+	SubscriptionID  string       `json:"subscription_id,omitempty"`
+	Name            string       `json:"name"`
+	Type            string       `json:"type"`
+	Amount          money.Amount `json:"amount"`
+	OriginalAmount  money.Amount `json:"original_amount"`
+	Currency        string       `json:"currency"`
+	CategoryID      *uint        `json:"category_id,omitempty"`
+	Time            time.Time    `json:"time"`
+}
+
+// ForecastDay 的 Income、Expense 包含计划收支和按历史分类均值估算的日常收支（Baseline*）
+type ForecastDay struct {
+	Date            string         `json:"date"`
+	Income          money.Amount   `json:"income"`
+	Expense         money.Amount   `json:"expense"`
+	BaselineIncome  money.Amount   `json:"baseline_income"`
+	BaselineExpense money.Amount   `json:"baseline_expense"`
+	Balance         money.Amount   `json:"balance"`
+	Items           []ForecastItem `json:"items"`
+}
+
+type ForecastCategoryAverage struct {
+	CategoryID   uint         `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Type         string       `json:"type"`
+	DailyAverage money.Amount `json:"daily_average"`
+}
+
+type ForecastResponse struct {
+	Currency         string                    `json:"currency"`
+	StartDate        string                    `json:"start_date"`
+	EndDate          string                    `json:"end_date"`
+	OpeningBalance   money.Amount              `json:"opening_balance"`
+	ClosingBalance   money.Amount              `json:"closing_balance"`
+	TotalIncome      money.Amount              `json:"total_income"`
+	TotalExpense     money.Amount              `json:"total_expense"`
+	LowestBalance    money.Amount              `json:"lowest_balance"`
+	LowestBalanceOn  string                    `json:"lowest_balance_on"`
+	CategoryAverages []ForecastCategoryAverage `json:"category_averages"`
+	MissingRates     []string                  `json:"missing_rates,omitempty"`
+	Days             []ForecastDay             `json:"days"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+	"finmind-backend/money"
+)
+
+// Goal 是储蓄目标，以用户本位币计。被标记到目标的账单计为存入或取出，关联账户后进出该账户的转账也自动计入
+type Goal struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	UserID       uint           `json:"user_id" gorm:"not null;index"`
+	Name         string         `json:"name" gorm:"not null"`
+	TargetAmount money.Amount   `json:"target_amount" gorm:"column:target_amount_minor;not null;check:target_amount_minor > 0"`
+	Deadline     *time.Time     `json:"deadline,omitempty"`
+	AccountID    *uint          `json:"account_id,omitempty" gorm:"index"`
+	Icon         string         `json:"icon"`
+	Color        string         `json:"color"`
+	Archived     bool           `json:"archived" gorm:"not null;default:false"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	User    User     `json:"-" gorm:"foreignKey:UserID"`
+	Account *Account `json:"-" gorm:"foreignKey:AccountID"`
+}
+
+type GoalResponse struct {
+	ID           uint         `json:"id"`
+	Name         string       `json:"name"`
+	TargetAmount money.Amount `json:"target_amount"`
+	Deadline     string       `json:"deadline,omitempty"`
+	AccountID    *uint        `json:"account_id,omitempty"`
+	Icon         string       `json:"icon"`
+	Color        string       `json:"color"`
+	Archived     bool         `json:"archived"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+
+	Progress GoalProgress `json:"progress"`
+}
+
+// GoalProgress 中的 RequiredMonthly 为按截止日期剩余月数平均后每月还需存入的金额，
+// ProjectedCompletion 按历史月均净存入推算，净存入不为正时为空
+type GoalProgress struct {
+	Currency            string       `json:"currency"`
+	Saved               money.Amount `json:"saved"`
+	Remaining           money.Amount `json:"remaining"`
+	Percent             float64      `json:"percent"`
+	Completed           bool         `json:"completed"`
+	ContributionCount   int          `json:"contribution_count"`
+	MonthlyAverage      money.Amount `json:"monthly_average"`
+	MonthsLeft          int          `json:"months_left,omitempty"`
+	RequiredMonthly     money.Amount `json:"required_monthly,omitempty"`
+	ProjectedCompletion string       `json:"projected_completion,omitempty"`
+	OnTrack             *bool        `json:"on_track,omitempty"`
+	MissingRates        int          `json:"missing_rates,omitempty"`
+}
+
+func (g *Goal) ToResponse(progress GoalProgress) GoalResponse {
+	response := GoalResponse{
+		ID:           g.ID,
+		Name:         g.Name,
+		TargetAmount: g.TargetAmount,
+		AccountID:    g.AccountID,
+		Icon:         g.Icon,
+		Color:        g.Color,
+		Archived:     g.Archived,
+		CreatedAt:    g.CreatedAt,
+		UpdatedAt:    g.UpdatedAt,
+		Progress:     progress,
+	}
+	if g.Deadline != nil {
+		response.Deadline = g.Deadline.Format("2006-01-02")
+	}
+	return response
+}
+
+type CreateGoalRequest struct {
+	Name         string       `json:"name" binding:"required,max=100"`
+	TargetAmount money.Amount `json:"target_amount" binding:"required,gt=0"`
+	Deadline     string       `json:"deadline" binding:"omitempty,datetime=2006-01-02"`
+	AccountID    *uint        `json:"account_id"`
+	Icon         string       `json:"icon"`
+	Color        string       `json:"color"`
+}
+
+type UpdateGoalRequest struct {
+	Name         string       `json:"name" binding:"omitempty,max=100"`
+	TargetAmount money.Amount `json:"target_amount" binding:"omitempty,gt=0"`
+	Deadline     *string      `json:"deadline" binding:"omitempty"`
+	AccountID    *uint        `json:"account_id"`
+	Icon         string       `json:"icon"`
+	Color        string       `json:"color"`
+	Archived     *bool        `json:"archived"`
+}
+
+type GoalContribution struct {
+	BillID    uint         `json:"bill_id"`
+	Type      string       `json:"type"`
+	Amount    money.Amount `json:"amount"`
+	Currency  string       `json:"currency"`
+	Converted money.Amount `json:"converted"`
+	Merchant  string       `json:"merchant"`
+	Tagged    bool         `json:"tagged"`
+	Time      time.Time    `json:"time"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type UserIdentity struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Provider    string    `json:"provider" gorm:"not null;uniqueIndex:idx_identities_provider_subject"`
+	Subject     string    `json:"subject" gorm:"not null;uniqueIndex:idx_identities_provider_subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+type OIDCAuthState struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Provider      string     `json:"provider" gorm:"not null"`
+	StateHash     string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	Nonce         string     `json:"-" gorm:"not null"`
+	CodeChallenge string     `json:"-" gorm:"not null;size:43"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+	"finmind-backend/money"
+)
+
+const NotificationTypeBudgetThreshold = "budget_threshold"
+
+// Notification 是站内通知，由站内通知渠道写入，客户端通过通知列表拉取
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Type      string     `json:"type" gorm:"not null"`
+	Title     string     `json:"title" gorm:"not null"`
+	Body      string     `json:"body"`
+	BudgetID  *uint      `json:"budget_id,omitempty" gorm:"index"`
+	ReadAt    *time.Time `json:"read_at,omitempty" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// BudgetAlert 记录预算在某个周期内已触发的阈值，唯一索引保证每个阈值每个周期只提醒一次
+type BudgetAlert struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	UserID      uint         `json:"user_id" gorm:"not null;index"`
+	BudgetID    uint         `json:"budget_id" gorm:"not null;uniqueIndex:idx_budget_alerts_period"`
+	PeriodStart string       `json:"period_start" gorm:"size:10;not null;uniqueIndex:idx_budget_alerts_period"`
+	Threshold   int          `json:"threshold" gorm:"not null;uniqueIndex:idx_budget_alerts_period"`
+	Spent       money.Amount `json:"spent" gorm:"column:spent_minor;not null"`
+	Available   money.Amount `json:"available" gorm:"column:available_minor;not null"`
+	CreatedAt   time.Time    `json:"created_at"`
+
+	User   User   `json:"-" gorm:"foreignKey:UserID"`
+	Budget Budget `json:"-" gorm:"foreignKey:BudgetID"`
+}
+
+type NotificationsQuery struct {
+	UnreadOnly bool `form:"unread_only"`
+	Limit      int  `form:"limit,default=50" binding:"min=1,max=200"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const PersonalAccessTokenPrefix = "fmp_"
+
+const (
+	ScopeBillsRead          = "bills:read"
+	ScopeBillsWrite         = "bills:write"
+	ScopeCategoriesRead     = "categories:read"
+	ScopeCategoriesWrite    = "categories:write"
+	ScopeStatsRead          = "stats:read"
+	ScopeAccountsRead       = "accounts:read"
+	ScopeAccountsWrite      = "accounts:write"
+	ScopeRatesRead          = "rates:read"
+	ScopeRatesWrite         = "rates:write"
+	ScopeBudgetsRead        = "budgets:read"
+	ScopeBudgetsWrite       = "budgets:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+	ScopeGoalsRead          = "goals:read"
+	ScopeGoalsWrite         = "goals:write"
+	ScopeRecurringRead      = "recurring:read"
+	ScopeRecurringWrite     = "recurring:write"
+)
+
+var AvailableScopes = []string{
+	ScopeBillsRead,
+	ScopeBillsWrite,
+	ScopeCategoriesRead,
+	ScopeCategoriesWrite,
+	ScopeStatsRead,
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeRatesRead,
+	ScopeRatesWrite,
+	ScopeBudgetsRead,
+	ScopeBudgetsWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+	ScopeGoalsRead,
+	ScopeGoalsWrite,
+	ScopeRecurringRead,
+	ScopeRecurringWrite,
+}
+
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	TokenHint  string     `json:"token_hint"`
+	Scopes     string     `json:"scopes" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	TokenHint  string     `json:"token_hint"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *PersonalAccessToken) ToResponse() PersonalAccessTokenResponse {
+	return PersonalAccessTokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		TokenHint:  t.TokenHint,
+		Scopes:     t.ScopeList(),
+		LastUsedAt: t.LastUsedAt,
+		ExpiresAt:  t.ExpiresAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func IsValidScope(scope string) bool {
+	for _, s := range AvailableScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
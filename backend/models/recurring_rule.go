@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+	"finmind-backend/money"
+)
+
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+// BillTemplate 是周期规则每次生成账单时使用的模板
+type BillTemplate struct {
+	Type        string       `json:"type" gorm:"not null"`
+	Amount      money.Amount `json:"amount" gorm:"column:amount_minor;not null"`
+	Currency    string       `json:"currency" gorm:"size:3;not null"`
+	CategoryID  *uint        `json:"category_id,omitempty"`
+	AccountID   *uint        `json:"account_id,omitempty"`
+	ToAccountID *uint        `json:"to_account_id,omitempty"`
+	GoalID      *uint        `json:"goal_id,omitempty"`
+	Channel     string       `json:"channel"`
+	Merchant    string       `json:"merchant"`
+	Description string       `json:"description"`
+}
+
+// RecurringRule 按类似 RRULE 的规则（频率、间隔、每月几号、截止日期或次数）定期生成账单。
+// 第 n 次发生时间由 StartDate 推算，不随生成时间漂移；NextOccurrence 为空表示规则已结束
+type RecurringRule struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	UserID         uint           `json:"user_id" gorm:"not null;index"`
+	Name           string         `json:"name" gorm:"not null"`
+	Frequency      string         `json:"frequency" gorm:"not null;check:frequency IN ('daily','weekly','monthly','yearly')"`
+	Interval       int            `json:"interval" gorm:"not null;default:1;check:interval > 0"`
+	DayOfMonth     int            `json:"day_of_month" gorm:"not null;default:0"`
+	StartDate      time.Time      `json:"start_date" gorm:"not null"`
+	EndDate        *time.Time     `json:"end_date,omitempty"`
+	Count          int            `json:"count" gorm:"not null;default:0"`
+	GeneratedCount int            `json:"generated_count" gorm:"not null;default:0"`
+	LastOccurrence *time.Time     `json:"last_occurrence,omitempty"`
+	NextOccurrence *time.Time     `json:"next_occurrence,omitempty" gorm:"index"`
+	Paused         bool           `json:"paused" gorm:"not null;default:false"`
+	Template       BillTemplate   `json:"template" gorm:"embedded;embeddedPrefix:template_"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+type CreateRecurringRuleRequest struct {
+	Name        string       `json:"name" binding:"required,max=100"`
+	Frequency   string       `json:"frequency" binding:"required,oneof=daily weekly monthly yearly"`
+	Interval    int          `json:"interval" binding:"omitempty,min=1,max=366"`
+	DayOfMonth  int          `json:"day_of_month" binding:"omitempty,min=1,max=31"`
+	StartDate   time.Time    `json:"start_date" binding:"required"`
+	EndDate     string       `json:"end_date" binding:"omitempty,datetime=2006-01-02"`
+	Count       int          `json:"count" binding:"omitempty,min=1"`
+	Type        string       `json:"type" binding:"required,oneof=income expense transfer"`
+	Amount      money.Amount `json:"amount" binding:"required,gt=0"`
+	Currency    string       `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	CategoryID  uint         `json:"category_id" binding:"required_unless=Type transfer"`
+	AccountID   *uint        `json:"account_id"`
+	ToAccountID *uint        `json:"to_account_id"`
+	GoalID      *uint        `json:"goal_id"`
+	Channel     string       `json:"channel" binding:"max=50"`
+	Merchant    string       `json:"merchant" binding:"required_unless=Type transfer"`
+	Description string       `json:"description"`
+}
+
+type UpdateRecurringRuleRequest struct {
+	Name        string       `json:"name" binding:"omitempty,max=100"`
+	Frequency   string       `json:"frequency" binding:"omitempty,oneof=daily weekly monthly yearly"`
+	Interval    int          `json:"interval" binding:"omitempty,min=1,max=366"`
+	DayOfMonth  *int         `json:"day_of_month" binding:"omitempty,min=0,max=31"`
+	StartDate   time.Time    `json:"start_date"`
+	EndDate     *string      `json:"end_date"`
+	Count       *int         `json:"count" binding:"omitempty,min=0"`
+	Paused      *bool        `json:"paused"`
+	Type        string       `json:"type" binding:"omitempty,oneof=income expense transfer"`
+	Amount      money.Amount `json:"amount" binding:"omitempty,gt=0"`
+	Currency    string       `json:"currency" binding:"omitempty,iso4217,supported_currency"`
+	CategoryID  uint         `json:"category_id"`
+	AccountID   *uint        `json:"account_id"`
+	ToAccountID *uint        `json:"to_account_id"`
+	GoalID      *uint        `json:"goal_id"`
+	Channel     string       `json:"channel" binding:"max=50"`
+	Merchant    string       `json:"merchant"`
+	Description string       `json:"description"`
+}
+
+type RecurringRulePreviewQuery struct {
+	Limit int `form:"limit,default=12" binding:"min=1,max=100"`
+}
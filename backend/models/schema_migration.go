@@ -0,0 +1,8 @@
+package models
+
+import "time"
+
+type SchemaMigration struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:100"`
+	AppliedAt time.Time `json:"applied_at" gorm:"not null"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Device     string     `json:"device"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	IssuedAt   time.Time  `json:"issued_at" gorm:"not null"`
+	LastUsedAt time.Time  `json:"last_used_at" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+type RefreshToken struct {
+	JTI        string     `json:"jti" gorm:"primaryKey;size:64"`
+	SessionID  uint       `json:"session_id" gorm:"not null;index"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	ParentJTI  string     `json:"parent_jti,omitempty" gorm:"size:64"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	IssuedAt   time.Time `json:"issued_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func (s *Session) ToResponse(currentSessionID uint) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		Device:     s.Device,
+		IPAddress:  s.IPAddress,
+		UserAgent:  s.UserAgent,
+		IssuedAt:   s.IssuedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID == currentSessionID,
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type SigningKey struct {
+	Kid        string     `json:"kid" gorm:"primaryKey;size:64"`
+	Algorithm  string     `json:"algorithm" gorm:"not null"`
+	PrivateKey []byte     `json:"-" gorm:"not null"`
+	PublicKey  []byte     `json:"-" gorm:"not null"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+	"finmind-backend/money"
+)
+
+// SubscriptionCandidate 是从账单历史中识别出的疑似订阅（周期扣费），尚未设置为周期账单
+type SubscriptionCandidate struct {
+	ID           string       `json:"id"`
+	Merchant     string       `json:"merchant"`
+	Frequency    string       `json:"frequency"`
+	Amount       money.Amount `json:"amount"`
+	Currency     string       `json:"currency"`
+	CategoryID   *uint        `json:"category_id,omitempty"`
+	AccountID    *uint        `json:"account_id,omitempty"`
+	ChargeCount  int          `json:"charge_count"`
+	FirstCharge  time.Time    `json:"first_charge"`
+	LastCharge   time.Time    `json:"last_charge"`
+	NextExpected time.Time    `json:"next_expected"`
+	AnnualCost   money.Amount `json:"annual_cost"`
+	Confidence   float64      `json:"confidence"`
+	BillIDs      []uint       `json:"bill_ids"`
+}
+
+type SubscriptionsQuery struct {
+	MinConfidence float64 `form:"min_confidence,default=0.5" binding:"min=0,max=1"`
+}
+
+type ConvertSubscriptionRequest struct {
+	Name string `json:"name" binding:"max=100"`
+}
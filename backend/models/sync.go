@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	SyncActionCreated = "created"
+	SyncActionUpdated = "updated"
+	SyncActionDeleted = "deleted"
+)
+
+type SyncChangesQuery struct {
+	Since string `form:"since"`
+	Limit int    `form:"limit,default=200" binding:"min=1,max=500"`
+}
+
+type SyncChange[T any] struct {
+	Action    string    `json:"action"`
+	ID        uint      `json:"id"`
+	ChangedAt time.Time `json:"changed_at"`
+	Data      T         `json:"data"`
+}
+
+type SyncChangesResponse struct {
+	Bills      []SyncChange[BillResponse]    `json:"bills"`
+	Categories []SyncChange[Category]        `json:"categories"`
+	Accounts   []SyncChange[AccountResponse] `json:"accounts"`
+	Cursor     string                        `json:"cursor"`
+	HasMore    bool                          `json:"has_more"`
+	ServerTime time.Time                     `json:"server_time"`
+}
@@ -6,34 +6,53 @@ import (
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"`
-	Avatar    string         `json:"avatar"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  uint           `json:"id" gorm:"primaryKey"`
+	Name                string         `json:"name" gorm:"not null"`
+	Email               string         `json:"email" gorm:"uniqueIndex;not null"`
+	Password            string         `json:"-" gorm:"not null"`
+	PasswordSetAt       *time.Time     `json:"-"`
+	Avatar              string         `json:"avatar"`
+	BaseCurrency        string         `json:"base_currency" gorm:"size:3;not null;default:'CNY'"`
+	EmailVerifiedAt     *time.Time     `json:"email_verified_at,omitempty"`
+	TOTPSecret          string         `json:"-"`
+	TOTPEnabledAt       *time.Time     `json:"-"`
+	TOTPLastStep        int64          `json:"-" gorm:"not null;default:0"`
+	DeletionScheduledAt *time.Time     `json:"deletion_scheduled_at,omitempty" gorm:"index"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Bills []Bill `json:"bills,omitempty" gorm:"foreignKey:UserID"`
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Avatar    string    `json:"avatar"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  uint       `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	Avatar              string     `json:"avatar"`
+	BaseCurrency        string     `json:"base_currency"`
+	EmailVerified       bool       `json:"email_verified"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`
+	HasPassword         bool       `json:"has_password"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		Avatar:    u.Avatar,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:                  u.ID,
+		Name:                u.Name,
+		Email:               u.Email,
+		Avatar:              u.Avatar,
+		BaseCurrency:        u.BaseCurrency,
+		EmailVerified:       u.EmailVerifiedAt != nil,
+		EmailVerifiedAt:     u.EmailVerifiedAt,
+		HasPassword:         u.PasswordSetAt != nil,
+		TwoFactorEnabled:    u.TOTPEnabledAt != nil,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		CreatedAt:           u.CreatedAt,
+		UpdatedAt:           u.UpdatedAt,
 	}
-}
\ No newline at end of file
+}
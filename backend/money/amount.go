@@ -0,0 +1,166 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale 是金额的最小单位数，所有币种统一按 1/100 存储（如分、美分），日元等无辅币的币种同样适用，
+// 三位小数的币种不受支持，见 SupportsCurrency
+const Scale = 100
+
+var ErrInvalidAmount = errors.New("invalid amount: expected a decimal number with at most 2 decimal places")
+
+// Amount 以最小单位的整数存储金额，避免浮点累加误差。JSON 中仍表示为十进制数字（如 25.5），与旧版本客户端兼容
+type Amount int64
+
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+func Parse(text string) (Amount, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	// big.Rat 按十进制文本精确解析，不经过 float64。它同时接受分数（1/2）和指数（1e3），这里只允许普通小数
+	if !isDecimal(text) {
+		return 0, ErrInvalidAmount
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return 0, ErrInvalidAmount
+	}
+	r.Mul(r, big.NewRat(Scale, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+	return Amount(r.Num().Int64()), nil
+}
+
+// isDecimal 判断文本是否为可带正负号的普通小数，如 "25"、"-25.50"，小数点两侧都必须有数字
+func isDecimal(text string) bool {
+	if text[0] == '+' || text[0] == '-' {
+		text = text[1:]
+	}
+	whole, fraction, hasPoint := strings.Cut(text, ".")
+	return isDigits(whole) && (!hasPoint || isDigits(fraction))
+}
+
+func isDigits(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// Mul 按比例换算金额（如汇率），结果四舍五入到最小单位
+func (a Amount) Mul(factor float64) Amount {
+	return Amount(math.Round(float64(a) * factor))
+}
+
+func (a Amount) Float64() float64 {
+	return float64(a) / Scale
+}
+
+// String 输出不带多余零的十进制文本，如 2550 -> "25.5"，2500 -> "25"
+func (a Amount) String() string {
+	minor := int64(a)
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	whole := strconv.FormatInt(minor/Scale, 10)
+	fraction := minor % Scale
+	if fraction == 0 {
+		return sign + whole
+	}
+	return sign + whole + "." + strings.TrimRight(fmt.Sprintf("%02d", fraction), "0")
+}
+
+// Fixed 输出固定两位小数，用于 CSV 等面向表格的导出
+func (a Amount) Fixed() string {
+	minor := int64(a)
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/Scale, minor%Scale)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	text = strings.Trim(text, `"`)
+
+	parsed, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
+
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = Amount(v)
+	case float64:
+		// SUM 等聚合在部分数据库中返回浮点或 numeric，整数值可以精确还原
+		*a = Amount(math.Round(v))
+	case []byte:
+		return a.scanText(string(v))
+	case string:
+		return a.scanText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money.Amount", value)
+	}
+	return nil
+}
+
+func (a *Amount) scanText(text string) error {
+	minor, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(text, 64)
+		if ferr != nil {
+			return err
+		}
+		minor = int64(math.Round(f))
+	}
+	*a = Amount(minor)
+	return nil
+}
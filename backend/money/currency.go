@@ -0,0 +1,24 @@
+package money
+
+// 所有币种统一以 1/Scale 为最小单位存储，只能精确表示最多两位小数的币种。
+// 以下是 ISO 4217 中小数位数不是 2 的币种，其余币种均为两位小数
+var currencyDigits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyDigits 返回币种按 ISO 4217 规定的小数位数
+func CurrencyDigits(code string) int {
+	if digits, ok := currencyDigits[code]; ok {
+		return digits
+	}
+	return 2
+}
+
+// SupportsCurrency 判断币种的金额能否按 1/Scale 精确存储：三位及以上小数的币种（如 KWD、BHD）不受支持。
+// 日元等没有辅币的币种同样按 1/100 存储，小数部分通常为零
+func SupportsCurrency(code string) bool {
+	return CurrencyDigits(code) <= 2
+}
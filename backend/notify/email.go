@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"finmind-backend/mailer"
+	"finmind-backend/models"
+)
+
+type EmailNotifier struct {
+	mailer mailer.Mailer
+}
+
+func NewEmailNotifier(m mailer.Mailer) *EmailNotifier {
+	return &EmailNotifier{mailer: m}
+}
+
+func (n *EmailNotifier) Name() string {
+	return ChannelEmail
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, user models.User, notification models.Notification) error {
+	return n.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: notification.Title,
+		Body:    fmt.Sprintf("Hi %s,\n\n%s\n", user.Name, notification.Body),
+	})
+}
@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+
+	"finmind-backend/models"
+	"gorm.io/gorm"
+)
+
+// InAppNotifier 把通知写入 notifications 表，供客户端在通知列表中查看
+type InAppNotifier struct {
+	db *gorm.DB
+}
+
+func NewInAppNotifier(db *gorm.DB) *InAppNotifier {
+	return &InAppNotifier{db: db}
+}
+
+func (n *InAppNotifier) Name() string {
+	return ChannelInApp
+}
+
+func (n *InAppNotifier) Notify(ctx context.Context, user models.User, notification models.Notification) error {
+	notification.ID = 0
+	notification.UserID = user.ID
+	return n.db.WithContext(ctx).Create(&notification).Error
+}
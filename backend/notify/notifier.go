@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"finmind-backend/config"
+	"finmind-backend/mailer"
+	"finmind-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ChannelInApp   = "inapp"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Notifier 把通知投递到某个渠道。Notification 尚未持久化，ID 为 0
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, user models.User, notification models.Notification) error
+}
+
+// Multi 依次投递到所有渠道，单个渠道失败只记录日志，不影响其他渠道
+type Multi []Notifier
+
+func (m Multi) Name() string {
+	names := make([]string, len(m))
+	for i, notifier := range m {
+		names[i] = notifier.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (m Multi) Notify(ctx context.Context, user models.User, notification models.Notification) error {
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, user, notification); err != nil {
+			log.Printf("[Notify] Channel %s failed for user %d: %v", notifier.Name(), user.ID, err)
+		}
+	}
+	return nil
+}
+
+// New 按 NOTIFICATION_CHANNELS 组装通知渠道，未知渠道和缺少配置的 webhook 会被忽略
+func New(cfg *config.Config, db *gorm.DB, m mailer.Mailer) Notifier {
+	var notifiers Multi
+	for _, channel := range cfg.NotificationChannels {
+		switch strings.ToLower(strings.TrimSpace(channel)) {
+		case ChannelInApp:
+			notifiers = append(notifiers, NewInAppNotifier(db))
+		case ChannelEmail:
+			notifiers = append(notifiers, NewEmailNotifier(m))
+		case ChannelWebhook:
+			if cfg.NotificationWebhookURL == "" {
+				log.Printf("[Notify] Webhook channel enabled without NOTIFICATION_WEBHOOK_URL, skipping")
+				continue
+			}
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.NotificationWebhookURL, cfg.NotificationWebhookSecret))
+		case "":
+		default:
+			log.Printf("[Notify] Unknown notification channel %q, skipping", channel)
+		}
+	}
+	return notifiers
+}
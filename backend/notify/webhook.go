@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"finmind-backend/models"
+)
+
+const webhookSignatureHeader = "X-FinMind-Signature"
+
+// WebhookNotifier 以 JSON POST 到固定地址。配置了密钥时用 HMAC-SHA256 对请求体签名，接收方据此校验来源
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+type webhookPayload struct {
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	BudgetID  *uint     `json:"budget_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, secret: secret, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return ChannelWebhook
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, user models.User, notification models.Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		BudgetID:  notification.BudgetID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
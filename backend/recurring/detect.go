@@ -0,0 +1,199 @@
+package recurring
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"finmind-backend/models"
+	"finmind-backend/money"
+)
+
+// SubscriptionLookback 是识别订阅时回看的账单时长，需覆盖至少两次年度扣费
+const SubscriptionLookback = 3 * 365 * 24 * time.Hour
+
+// 同一商户金额相差不超过 20% 视为同一笔订阅，允许小幅调价或汇率波动
+const amountSimilarity = 1.2
+
+type period struct {
+	frequency  string
+	days       float64
+	tolerance  float64
+	perYear    int64
+	minCharges int
+}
+
+var periods = []period{
+	{models.FrequencyWeekly, 7, 2, 52, 4},
+	{models.FrequencyMonthly, 365.25 / 12, 4, 12, 3},
+	{models.FrequencyYearly, 365.25, 15, 1, 2},
+}
+
+// DetectSubscriptions 按规范化后的商户名和币种分组、再按金额相近程度聚类，
+// 对扣费间隔呈每周、每月或每年规律的分组给出订阅候选。bills 应为按时间升序的支出账单，
+// 距最后一次扣费已超过两个周期的视为已取消，不再返回
+func DetectSubscriptions(bills []models.Bill, now time.Time) []models.SubscriptionCandidate {
+	groups := make(map[string][]models.Bill)
+	var keys []string
+	for _, bill := range bills {
+		merchant := NormalizeMerchant(bill.Merchant)
+		if merchant == "" {
+			continue
+		}
+		key := merchant + "|" + bill.Currency
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], bill)
+	}
+
+	candidates := []models.SubscriptionCandidate{}
+	for _, key := range keys {
+		for _, cluster := range clusterByAmount(groups[key]) {
+			if candidate, ok := detectPeriod(key, cluster, now); ok {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		return candidates[i].AnnualCost > candidates[j].AnnualCost
+	})
+	return candidates
+}
+
+// NormalizeMerchant 转为小写并去掉数字和标点，使 "NETFLIX.COM 8841" 与 "Netflix.com" 归为同一商户
+func NormalizeMerchant(merchant string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(merchant) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// clusterByAmount 按金额升序切分，每个分组内的金额不超过组内最小金额的 amountSimilarity 倍，
+// 返回的分组内账单按时间升序
+func clusterByAmount(bills []models.Bill) [][]models.Bill {
+	sorted := make([]models.Bill, len(bills))
+	copy(sorted, bills)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount < sorted[j].Amount })
+
+	var clusters [][]models.Bill
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i < len(sorted) && float64(sorted[i].Amount) <= float64(sorted[start].Amount)*amountSimilarity {
+			continue
+		}
+		cluster := sorted[start:i]
+		sort.SliceStable(cluster, func(a, b int) bool { return cluster[a].BillTime.Before(cluster[b].BillTime) })
+		clusters = append(clusters, cluster)
+		start = i
+	}
+	return clusters
+}
+
+// detectPeriod 以扣费间隔的中位数判断周期。置信度综合间隔规律性（60%）、金额一致性（20%）和扣费次数（20%）
+func detectPeriod(key string, bills []models.Bill, now time.Time) (models.SubscriptionCandidate, bool) {
+	if len(bills) < 2 {
+		return models.SubscriptionCandidate{}, false
+	}
+
+	intervals := make([]float64, len(bills)-1)
+	for i := 1; i < len(bills); i++ {
+		intervals[i-1] = bills[i].BillTime.Sub(bills[i-1].BillTime).Hours() / 24
+	}
+
+	var matched *period
+	medianInterval := median(intervals)
+	for i := range periods {
+		if math.Abs(medianInterval-periods[i].days) <= periods[i].tolerance {
+			matched = &periods[i]
+			break
+		}
+	}
+	if matched == nil || len(bills) < matched.minCharges {
+		return models.SubscriptionCandidate{}, false
+	}
+
+	first, last := bills[0], bills[len(bills)-1]
+	if now.Sub(last.BillTime).Hours()/24 > 2*matched.days+matched.tolerance {
+		return models.SubscriptionCandidate{}, false
+	}
+
+	regular := 0
+	for _, interval := range intervals {
+		if math.Abs(interval-matched.days) <= matched.tolerance {
+			regular++
+		}
+	}
+	amounts := make([]float64, len(bills))
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for i, bill := range bills {
+		amounts[i] = float64(bill.Amount)
+		lowest = math.Min(lowest, amounts[i])
+		highest = math.Max(highest, amounts[i])
+	}
+	typical := median(amounts)
+	spread := (highest - lowest) / typical
+
+	regularity := float64(regular) / float64(len(intervals))
+	consistency := math.Max(0, 1-spread)
+	history := math.Min(1, float64(len(intervals))/float64(matched.minCharges+1))
+	confidence := math.Round((0.6*regularity+0.2*consistency+0.2*history)*100) / 100
+
+	amount := money.Amount(math.Round(typical))
+	candidate := models.SubscriptionCandidate{
+		ID:          candidateID(key, first.ID),
+		Merchant:    last.Merchant,
+		Frequency:   matched.frequency,
+		Amount:      amount,
+		Currency:    last.Currency,
+		CategoryID:  last.CategoryID,
+		AccountID:   last.AccountID,
+		ChargeCount: len(bills),
+		FirstCharge: first.BillTime,
+		LastCharge:  last.BillTime,
+		AnnualCost:  amount * money.Amount(matched.perYear),
+		Confidence:  confidence,
+		BillIDs:     make([]uint, len(bills)),
+	}
+	for i, bill := range bills {
+		candidate.BillIDs[i] = bill.ID
+	}
+
+	// 以最后一次扣费为起点按周期推算，跳过已错过的扣费日
+	schedule := models.RecurringRule{Frequency: matched.frequency, Interval: 1, StartDate: last.BillTime}
+	if next := NextAfter(&schedule, &now); next != nil {
+		candidate.NextExpected = *next
+	}
+	return candidate, true
+}
+
+// candidateID 由分组键和分组内最早账单生成，账单历史不变时保持稳定，供转换接口定位候选
+func candidateID(key string, firstBillID uint) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, firstBillID)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,112 @@
+package recurring
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"finmind-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 单条规则每次最多补生成的账单数，剩余的留到下一轮，避免长时间停机后一次事务过大
+const maxCatchUpPerRun = 500
+
+// ClientID 是生成账单的幂等键：同一规则同一天只会生成一条账单，用户删除后也不会重新生成
+func ClientID(ruleID uint, occurrence time.Time) string {
+	return fmt.Sprintf("recurring-%d-%s", ruleID, occurrence.UTC().Format("20060102"))
+}
+
+// Materialize 为所有到期的规则生成账单，包括停机期间错过的发生时间，返回新生成的账单
+func Materialize(db *gorm.DB, now time.Time) ([]models.Bill, error) {
+	var rules []models.RecurringRule
+	if err := db.Where("paused = ? AND next_occurrence IS NOT NULL AND next_occurrence <= ?", false, now).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	var bills []models.Bill
+	for i := range rules {
+		created, err := MaterializeRule(db, &rules[i], now)
+		if err != nil {
+			log.Printf("[Recurring] Failed to materialize rule %d: %v", rules[i].ID, err)
+			continue
+		}
+		bills = append(bills, created...)
+	}
+	return bills, nil
+}
+
+// MaterializeRule 生成规则在 now 之前（含）所有未生成的账单。规则按 generated_count 做乐观锁，
+// 多个实例同时处理同一规则时只有一个能提交，其余回滚；账单的 client_id 唯一索引保证不会重复入账
+func MaterializeRule(db *gorm.DB, rule *models.RecurringRule, now time.Time) ([]models.Bill, error) {
+	if rule.Paused {
+		return nil, nil
+	}
+
+	var created []models.Bill
+	original := *rule
+	err := db.Transaction(func(tx *gorm.DB) error {
+		previousCount := original.GeneratedCount
+		for i := 0; i < maxCatchUpPerRun && rule.NextOccurrence != nil && !rule.NextOccurrence.After(now); i++ {
+			occurrence := *rule.NextOccurrence
+			bill := newBill(rule, occurrence)
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bill)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, bill)
+			}
+
+			rule.GeneratedCount++
+			rule.LastOccurrence = &occurrence
+			rule.NextOccurrence = NextAfter(rule, &occurrence)
+		}
+		if rule.GeneratedCount == previousCount {
+			return nil
+		}
+
+		result := tx.Model(&models.RecurringRule{}).
+			Where("id = ? AND generated_count = ?", rule.ID, previousCount).
+			Updates(map[string]interface{}{
+				"generated_count": rule.GeneratedCount,
+				"last_occurrence": rule.LastOccurrence,
+				"next_occurrence": rule.NextOccurrence,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("rule %d was updated concurrently", rule.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		*rule = original
+		return nil, err
+	}
+	return created, nil
+}
+
+func newBill(rule *models.RecurringRule, occurrence time.Time) models.Bill {
+	clientID := ClientID(rule.ID, occurrence)
+	template := rule.Template
+	return models.Bill{
+		UserID:          rule.UserID,
+		ClientID:        &clientID,
+		RecurringRuleID: &rule.ID,
+		Type:            template.Type,
+		Amount:          template.Amount,
+		Currency:        template.Currency,
+		CategoryID:      template.CategoryID,
+		AccountID:       template.AccountID,
+		ToAccountID:     template.ToAccountID,
+		GoalID:          template.GoalID,
+		Channel:         template.Channel,
+		Merchant:        template.Merchant,
+		Description:     template.Description,
+		BillTime:        occurrence,
+	}
+}
@@ -0,0 +1,108 @@
+package recurring
+
+import (
+	"time"
+
+	"finmind-backend/models"
+)
+
+// Occurrence 返回规则第 n 次（从 0 开始）的计划发生时间，时分秒取自 StartDate。
+// 按月和按年的规则在当月天数不足时取当月最后一天，如每月 31 号在二月落在 28 或 29 号
+func Occurrence(rule *models.RecurringRule, n int) time.Time {
+	start := rule.StartDate.UTC()
+	interval := rule.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch rule.Frequency {
+	case models.FrequencyDaily:
+		return start.AddDate(0, 0, n*interval)
+	case models.FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n*interval)
+	case models.FrequencyYearly:
+		return dateInMonth(start, start.Year()+n*interval, start.Month(), rule.DayOfMonth)
+	default:
+		months := int(start.Month()) - 1 + n*interval
+		return dateInMonth(start, start.Year()+months/12, time.Month(months%12+1), rule.DayOfMonth)
+	}
+}
+
+func dateInMonth(start time.Time, year int, month time.Month, dayOfMonth int) time.Time {
+	day := dayOfMonth
+	if day <= 0 {
+		day = start.Day()
+	}
+	if last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+}
+
+// NextAfter 返回晚于 after 的下一次发生时间（after 为空时返回第一次），规则已结束时返回 nil。
+// 结束条件为超过 EndDate（含当天）或已生成 Count 次
+func NextAfter(rule *models.RecurringRule, after *time.Time) *time.Time {
+	if rule.Count > 0 && rule.GeneratedCount >= rule.Count {
+		return nil
+	}
+
+	occurrence := Occurrence(rule, firstIndexAfter(rule, after))
+	if rule.EndDate != nil && !occurrence.Before(rule.EndDate.UTC().AddDate(0, 0, 1)) {
+		return nil
+	}
+	return &occurrence
+}
+
+// firstIndexAfter 返回第一个不早于 StartDate 且晚于 after 的发生序号。先按经过的天数、月数或年数
+// 直接估算，再前后微调（按月的规则在月末取值会有一次偏差），不随规则存续时间增长而变慢
+func firstIndexAfter(rule *models.RecurringRule, after *time.Time) int {
+	start := rule.StartDate.UTC()
+	valid := func(n int) bool {
+		occurrence := Occurrence(rule, n)
+		return !occurrence.Before(start) && (after == nil || occurrence.After(*after))
+	}
+
+	n := 0
+	if after != nil && after.After(start) {
+		n = elapsedPeriods(rule, start, after.UTC())
+	}
+	for n > 0 && valid(n-1) {
+		n--
+	}
+	for !valid(n) {
+		n++
+	}
+	return n
+}
+
+// elapsedPeriods 估算从 start 到 t 经过的周期数，与实际序号至多相差一
+func elapsedPeriods(rule *models.RecurringRule, start, t time.Time) int {
+	interval := rule.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch rule.Frequency {
+	case models.FrequencyDaily:
+		return int(t.Sub(start)/(24*time.Hour)) / interval
+	case models.FrequencyWeekly:
+		return int(t.Sub(start)/(7*24*time.Hour)) / interval
+	case models.FrequencyYearly:
+		return (t.Year() - start.Year()) / interval
+	default:
+		return ((t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())) / interval
+	}
+}
+
+// Preview 返回从 NextOccurrence 开始的至多 limit 次发生时间，不修改规则
+func Preview(rule *models.RecurringRule, limit int) []time.Time {
+	occurrences := []time.Time{}
+	simulated := *rule
+	next := rule.NextOccurrence
+	for next != nil && len(occurrences) < limit {
+		occurrences = append(occurrences, *next)
+		simulated.GeneratedCount++
+		next = NextAfter(&simulated, next)
+	}
+	return occurrences
+}
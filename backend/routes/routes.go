@@ -5,16 +5,56 @@ import (
 	"gorm.io/gorm"
 	"finmind-backend/config"
 	"finmind-backend/handlers"
+	"finmind-backend/jwtkeys"
+	"finmind-backend/mailer"
 	"finmind-backend/middleware"
+	"finmind-backend/models"
+	"finmind-backend/oidc"
+	"finmind-backend/throttle"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config) {
+func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config, keys *jwtkeys.Manager, mail mailer.Mailer, budgetAlerter *handlers.BudgetAlerter) {
+	registerValidators()
 	r.Use(middleware.CORSMiddleware(cfg))
 
 	if db != nil {
-		authHandler := handlers.NewAuthHandler(db, cfg)
+		loginGuard := throttle.NewLoginGuard(
+			throttle.NewStore(cfg.LoginThrottleStore, db),
+			throttle.Policy{
+				Threshold:   cfg.LoginMaxAccountFailures,
+				BaseLockout: cfg.LoginLockoutBase,
+				MaxLockout:  cfg.LoginLockoutMax,
+				Window:      cfg.LoginFailureWindow,
+			},
+			throttle.Policy{
+				Threshold:   cfg.LoginMaxIPFailures,
+				BaseLockout: cfg.LoginLockoutBase,
+				MaxLockout:  cfg.LoginLockoutMax,
+				Window:      cfg.LoginFailureWindow,
+			},
+		)
+		authHandler := handlers.NewAuthHandler(db, cfg, mail, loginGuard, keys)
+		oidcHandler := handlers.NewOIDCHandler(authHandler, oidcProviders(cfg))
 		categoryHandler := handlers.NewCategoryHandler(db)
-		billHandler := handlers.NewBillHandler(db)
+		billHandler := handlers.NewBillHandler(db, budgetAlerter)
+		billImportHandler := handlers.NewBillImportHandler(db, cfg, budgetAlerter)
+		accountHandler := handlers.NewAccountHandler(db)
+		exchangeRateHandler := handlers.NewExchangeRateHandler(db)
+		budgetHandler := handlers.NewBudgetHandler(db)
+		notificationHandler := handlers.NewNotificationHandler(db)
+		goalHandler := handlers.NewGoalHandler(db)
+		recurringRuleHandler := handlers.NewRecurringRuleHandler(db, budgetAlerter)
+		subscriptionHandler := handlers.NewSubscriptionHandler(db)
+		forecastHandler := handlers.NewForecastHandler(db)
+		syncHandler := handlers.NewSyncHandler(db)
+		authMiddleware := middleware.AuthMiddleware(keys, db)
+		requireVerifiedEmail := middleware.EmailVerificationMiddleware(cfg, db)
+		requireSession := middleware.RequireSession()
+
+		r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+			c.Header("Cache-Control", "public, max-age=300")
+			c.JSON(200, keys.JWKS())
+		})
 
 		api := r.Group("/api/v1")
 		{
@@ -22,19 +62,47 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			{
 				auth.POST("/register", authHandler.Register)
 				auth.POST("/login", authHandler.Login)
+				auth.POST("/mfa/verify", authHandler.VerifyMFA)
 				auth.POST("/refresh", authHandler.RefreshToken)
+				auth.POST("/forgot-password", authHandler.ForgotPassword)
+				auth.POST("/reset-password", authHandler.ResetPassword)
+				auth.POST("/verify-email", authHandler.VerifyEmail)
+				auth.GET("/oidc/providers", oidcHandler.GetProviders)
+				auth.GET("/oidc/:provider/authorize", oidcHandler.Authorize)
+				auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+				auth.POST("/oidc/:provider/callback", oidcHandler.Callback)
+				auth.POST("/resend-verification", authMiddleware, requireSession, authHandler.ResendVerification)
+				auth.GET("/validate", authMiddleware, authHandler.ValidateToken)
+				auth.POST("/logout", authMiddleware, requireSession, authHandler.Logout)
+				auth.POST("/logout-all", authMiddleware, requireSession, authHandler.LogoutAll)
 			}
 
 			protected := api.Group("/")
-			protected.Use(middleware.AuthMiddleware(cfg))
+			protected.Use(authMiddleware)
 			{
-				user := protected.Group("/user")
+				user := protected.Group("/user", requireSession)
 				{
 					user.GET("/profile", authHandler.GetProfile)
 					user.PUT("/profile", authHandler.UpdateProfile)
+					user.PUT("/password", authHandler.ChangePassword)
+					user.POST("/2fa/setup", authHandler.SetupTwoFactor)
+					user.POST("/2fa/enable", authHandler.EnableTwoFactor)
+					user.POST("/2fa/disable", authHandler.DisableTwoFactor)
+					user.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+					user.GET("/sessions", authHandler.GetSessions)
+					user.DELETE("/sessions/:id", authHandler.RevokeSession)
+					user.GET("/identities", oidcHandler.GetIdentities)
+					user.DELETE("/identities/:id", oidcHandler.DeleteIdentity)
+					user.GET("/tokens", authHandler.GetPersonalAccessTokens)
+					user.POST("/tokens", authHandler.CreatePersonalAccessToken)
+					user.DELETE("/tokens/:id", authHandler.RevokePersonalAccessToken)
+					user.GET("/export", authHandler.ExportData)
+					user.POST("/deletion", authHandler.RequestAccountDeletion)
+					user.DELETE("/deletion", authHandler.CancelAccountDeletion)
+					user.DELETE("", authHandler.DeleteAccount)
 				}
 
-				categories := protected.Group("/categories")
+				categories := protected.Group("/categories", requireVerifiedEmail, middleware.RequireScope(models.ScopeCategoriesRead, models.ScopeCategoriesWrite))
 				{
 					categories.GET("/", categoryHandler.GetCategories)
 					categories.POST("/", categoryHandler.CreateCategory)
@@ -42,14 +110,92 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config) {
 					categories.DELETE("/:id", categoryHandler.DeleteCategory)
 				}
 
-				bills := protected.Group("/bills")
+				// 统计接口单独分组，以便个人访问令牌仅凭 stats:read 即可访问
+				stats := protected.Group("/bills/statistics", requireVerifiedEmail, middleware.RequireScope(models.ScopeStatsRead, ""))
+				{
+					stats.GET("", billHandler.GetStatistics)
+				}
+
+				bills := protected.Group("/bills", requireVerifiedEmail, middleware.RequireScope(models.ScopeBillsRead, models.ScopeBillsWrite))
 				{
 					bills.GET("/", billHandler.GetBills)
 					bills.POST("/", billHandler.CreateBill)
+					bills.POST("/sync", billHandler.SyncBills)
+					bills.POST("/import", billImportHandler.ImportBills)
 					bills.GET("/:id", billHandler.GetBill)
 					bills.PUT("/:id", billHandler.UpdateBill)
 					bills.DELETE("/:id", billHandler.DeleteBill)
-					bills.GET("/statistics", billHandler.GetStatistics)
+				}
+
+				accounts := protected.Group("/accounts", requireVerifiedEmail, middleware.RequireScope(models.ScopeAccountsRead, models.ScopeAccountsWrite))
+				{
+					accounts.GET("/", accountHandler.GetAccounts)
+					accounts.POST("/", accountHandler.CreateAccount)
+					accounts.GET("/:id", accountHandler.GetAccount)
+					accounts.PUT("/:id", accountHandler.UpdateAccount)
+					accounts.DELETE("/:id", accountHandler.DeleteAccount)
+					accounts.GET("/:id/balances", accountHandler.GetAccountBalances)
+				}
+
+				budgets := protected.Group("/budgets", requireVerifiedEmail, middleware.RequireScope(models.ScopeBudgetsRead, models.ScopeBudgetsWrite))
+				{
+					budgets.GET("/", budgetHandler.GetBudgets)
+					budgets.POST("/", budgetHandler.CreateBudget)
+					budgets.GET("/progress", budgetHandler.GetBudgetProgress)
+					budgets.GET("/:id", budgetHandler.GetBudget)
+					budgets.PUT("/:id", budgetHandler.UpdateBudget)
+					budgets.DELETE("/:id", budgetHandler.DeleteBudget)
+				}
+
+				goals := protected.Group("/goals", requireVerifiedEmail, middleware.RequireScope(models.ScopeGoalsRead, models.ScopeGoalsWrite))
+				{
+					goals.GET("/", goalHandler.GetGoals)
+					goals.POST("/", goalHandler.CreateGoal)
+					goals.GET("/:id", goalHandler.GetGoal)
+					goals.PUT("/:id", goalHandler.UpdateGoal)
+					goals.DELETE("/:id", goalHandler.DeleteGoal)
+					goals.GET("/:id/contributions", goalHandler.GetGoalContributions)
+				}
+
+				recurringRules := protected.Group("/recurring-rules", requireVerifiedEmail, middleware.RequireScope(models.ScopeRecurringRead, models.ScopeRecurringWrite))
+				{
+					recurringRules.GET("/", recurringRuleHandler.GetRecurringRules)
+					recurringRules.POST("/", recurringRuleHandler.CreateRecurringRule)
+					recurringRules.GET("/:id", recurringRuleHandler.GetRecurringRule)
+					recurringRules.PUT("/:id", recurringRuleHandler.UpdateRecurringRule)
+					recurringRules.DELETE("/:id", recurringRuleHandler.DeleteRecurringRule)
+					recurringRules.GET("/:id/preview", recurringRuleHandler.PreviewRecurringRule)
+				}
+
+				subscriptions := protected.Group("/subscriptions", requireVerifiedEmail, middleware.RequireScope(models.ScopeBillsRead, models.ScopeRecurringWrite))
+				{
+					subscriptions.GET("/", subscriptionHandler.GetSubscriptions)
+					subscriptions.POST("/:id/convert", subscriptionHandler.ConvertSubscription)
+				}
+
+				forecast := protected.Group("/forecast", requireVerifiedEmail, middleware.RequireScope(models.ScopeStatsRead, ""))
+				{
+					forecast.GET("", forecastHandler.GetForecast)
+				}
+
+				notifications := protected.Group("/notifications", requireVerifiedEmail, middleware.RequireScope(models.ScopeNotificationsRead, models.ScopeNotificationsWrite))
+				{
+					notifications.GET("/", notificationHandler.GetNotifications)
+					notifications.PUT("/read-all", notificationHandler.MarkAllNotificationsRead)
+					notifications.PUT("/:id/read", notificationHandler.MarkNotificationRead)
+				}
+
+				exchangeRates := protected.Group("/exchange-rates", requireVerifiedEmail, middleware.RequireScope(models.ScopeRatesRead, models.ScopeRatesWrite))
+				{
+					exchangeRates.GET("/", exchangeRateHandler.GetExchangeRates)
+					exchangeRates.POST("/", exchangeRateHandler.CreateExchangeRate)
+					exchangeRates.GET("/convert", exchangeRateHandler.Convert)
+					exchangeRates.DELETE("/:id", exchangeRateHandler.DeleteExchangeRate)
+				}
+
+				sync := protected.Group("/sync", requireVerifiedEmail, middleware.RequireScope(models.ScopeBillsRead, ""))
+				{
+					sync.GET("/changes", syncHandler.GetChanges)
 				}
 			}
 		}
@@ -65,4 +211,19 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config) {
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "message": "FinMind API is running"})
 	})
-}
\ No newline at end of file
+}
+
+func oidcProviders(cfg *config.Config) map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDCProviders))
+	for name, providerCfg := range cfg.OIDCProviders {
+		providers[name] = oidc.NewProvider(oidc.Config{
+			Name:         name,
+			Issuer:       providerCfg.Issuer,
+			ClientID:     providerCfg.ClientID,
+			ClientSecret: providerCfg.ClientSecret,
+			RedirectURL:  providerCfg.RedirectURL,
+			Scopes:       providerCfg.Scopes,
+		}, nil)
+	}
+	return providers
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"finmind-backend/money"
+)
+
+// registerValidators 注册请求绑定使用的自定义校验：supported_currency 要求币种的金额能按最小单位精确存储
+func registerValidators() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	engine.RegisterValidation("supported_currency", func(fl validator.FieldLevel) bool {
+		return money.SupportsCurrency(fl.Field().String())
+	})
+}
@@ -0,0 +1,65 @@
+package throttle
+
+import (
+	"errors"
+	"time"
+
+	"finmind-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DatabaseStore struct {
+	db *gorm.DB
+}
+
+func NewDatabaseStore(db *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+func (s *DatabaseStore) Get(key string) (Entry, error) {
+	var attempt models.LoginAttempt
+	err := s.db.Where("key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Entry{}, nil
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return toEntry(attempt), nil
+}
+
+func (s *DatabaseStore) RecordFailure(key string, now time.Time, window time.Duration) (Entry, error) {
+	// 使用 upsert 保证多个节点并发累加时计数准确
+	attempt := models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END", now.Add(-window)),
+			"last_failure_at": now,
+		}),
+	}).Create(&attempt).Error; err != nil {
+		return Entry{}, err
+	}
+
+	return s.Get(key)
+}
+
+func (s *DatabaseStore) Lock(key string, until time.Time) error {
+	return s.db.Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (s *DatabaseStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+func toEntry(attempt models.LoginAttempt) Entry {
+	entry := Entry{
+		Failures:      attempt.Failures,
+		LastFailureAt: attempt.LastFailureAt,
+	}
+	if attempt.LockedUntil != nil {
+		entry.LockedUntil = *attempt.LockedUntil
+	}
+	return entry
+}
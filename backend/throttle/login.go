@@ -0,0 +1,87 @@
+package throttle
+
+import (
+	"strings"
+	"time"
+)
+
+type Policy struct {
+	Threshold   int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	Window      time.Duration
+}
+
+// lockout 达到阈值后按指数退避计算锁定时长
+func (p Policy) lockout(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+
+	lockout := p.BaseLockout
+	for i := p.Threshold; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+type LoginGuard struct {
+	store   Store
+	account Policy
+	ip      Policy
+}
+
+func NewLoginGuard(store Store, account, ip Policy) *LoginGuard {
+	return &LoginGuard{store: store, account: account, ip: ip}
+}
+
+func (g *LoginGuard) Check(email, ip string, now time.Time) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		entry, err := g.store.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if wait := entry.LockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return retryAfter, nil
+}
+
+func (g *LoginGuard) Fail(email, ip string, now time.Time) (time.Duration, error) {
+	var retryAfter time.Duration
+	for key, policy := range map[string]Policy{accountKey(email): g.account, ipKey(ip): g.ip} {
+		entry, err := g.store.RecordFailure(key, now, policy.Window)
+		if err != nil {
+			return 0, err
+		}
+
+		lockout := policy.lockout(entry.Failures)
+		if lockout == 0 {
+			continue
+		}
+		if err := g.store.Lock(key, now.Add(lockout)); err != nil {
+			return 0, err
+		}
+		if lockout > retryAfter {
+			retryAfter = lockout
+		}
+	}
+	return retryAfter, nil
+}
+
+func (g *LoginGuard) Succeed(email string) error {
+	return g.store.Reset(accountKey(email))
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,73 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+const memoryCleanupInterval = 10 * time.Minute
+
+type MemoryStore struct {
+	mu          sync.Mutex
+	entries     map[string]Entry
+	lastCleanup time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+func (s *MemoryStore) Get(key string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries[key], nil
+}
+
+func (s *MemoryStore) RecordFailure(key string, now time.Time, window time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanup(now, window)
+
+	entry := s.entries[key]
+	if now.Sub(entry.LastFailureAt) > window {
+		entry.Failures = 0
+	}
+	entry.Failures++
+	entry.LastFailureAt = now
+	s.entries[key] = entry
+
+	return entry, nil
+}
+
+func (s *MemoryStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	entry.LockedUntil = until
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) cleanup(now time.Time, window time.Duration) {
+	if now.Sub(s.lastCleanup) < memoryCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+
+	for key, entry := range s.entries {
+		if now.Sub(entry.LastFailureAt) > window && now.After(entry.LockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
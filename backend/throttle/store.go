@@ -0,0 +1,34 @@
+package throttle
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StoreMemory   = "memory"
+	StoreDatabase = "database"
+)
+
+type Entry struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store 保存失败计数，单节点使用内存实现，多节点部署时使用共享的数据库实现
+type Store interface {
+	Get(key string) (Entry, error)
+	// RecordFailure 累加失败次数，距上次失败超过 window 时重新计数
+	RecordFailure(key string, now time.Time, window time.Duration) (Entry, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+func NewStore(driver string, db *gorm.DB) Store {
+	if driver == StoreDatabase {
+		return NewDatabaseStore(db)
+	}
+	return NewMemoryStore()
+}